@@ -0,0 +1,69 @@
+// Copyright © 2022
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/metrics"
+	"github.com/equinor/radix-cluster-cleanup/pkg/settings"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var acknowledgeCircuitBreakerCommand = &cobra.Command{
+	Use:   "acknowledge-circuit-breaker",
+	Short: "Reset a tripped circuit breaker",
+	Long:  "Reset a tripped circuit breaker kept in the --circuit-breaker-configmap, so stops and deletions within the limits are allowed again",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		allowNextRun, err := cmd.Flags().GetBool(settings.AllowNextRunOption)
+		if err != nil {
+			return err
+		}
+		return acknowledgeCircuitBreaker(cmd.Context(), allowNextRun)
+	},
+}
+
+func init() {
+	acknowledgeCircuitBreakerCommand.Flags().Bool(settings.AllowNextRunOption, false, "allow the next run of the action the circuit breaker tripped on, regardless of the limits")
+	rootCmd.AddCommand(acknowledgeCircuitBreakerCommand)
+}
+
+func acknowledgeCircuitBreaker(ctx context.Context, allowNextRun bool) error {
+	kubeClient, err := getKubeUtil()
+	if err != nil {
+		return err
+	}
+	breaker, err := getCircuitBreaker(kubeClient)
+	if err != nil {
+		return err
+	}
+	if breaker == nil {
+		return fmt.Errorf("--%s is required to reset the circuit breaker kept in it", settings.CircuitBreakerConfigMapOption)
+	}
+	trip, err := breaker.Acknowledge(ctx, allowNextRun)
+	if err != nil {
+		return err
+	}
+	if trip == nil {
+		log.Ctx(ctx).Info().Msg("circuit breaker is not tripped")
+		return nil
+	}
+	metrics.SetCircuitBreakerTripped(false)
+	log.Ctx(ctx).Info().Bool("allowNextRun", allowNextRun).Msgf("reset circuit breaker tripped on %s at %s: %s", trip.Action, trip.At.Format(time.RFC3339), trip.Reason)
+	return nil
+}
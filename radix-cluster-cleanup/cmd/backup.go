@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/backup"
+	"github.com/equinor/radix-cluster-cleanup/pkg/settings"
+)
+
+const (
+	backupSinkDir = "dir"
+	backupSinkS3  = "s3"
+
+	s3AccessKeyIdEnvironmentVariable     = "AWS_ACCESS_KEY_ID"
+	s3SecretAccessKeyEnvironmentVariable = "AWS_SECRET_ACCESS_KEY"
+)
+
+// getBackupSink returns the backup sink configured by the backup sink option, or nil if none is configured
+func getBackupSink() (backup.Sink, error) {
+	sinkType, err := rootCmd.Flags().GetString(settings.BackupSinkOption)
+	if err != nil {
+		return nil, err
+	}
+	switch sinkType {
+	case "":
+		return nil, nil
+	case backupSinkDir:
+		dir, err := rootCmd.Flags().GetString(settings.BackupDirOption)
+		if err != nil {
+			return nil, err
+		}
+		if dir == "" {
+			return nil, fmt.Errorf("backup sink %s requires --%s", sinkType, settings.BackupDirOption)
+		}
+		return backup.NewDirSink(dir), nil
+	case backupSinkS3:
+		endpoint, endpointErr := rootCmd.Flags().GetString(settings.BackupS3EndpointOption)
+		bucket, bucketErr := rootCmd.Flags().GetString(settings.BackupS3BucketOption)
+		region, regionErr := rootCmd.Flags().GetString(settings.BackupS3RegionOption)
+		if err := errors.Join(endpointErr, bucketErr, regionErr); err != nil {
+			return nil, err
+		}
+		if endpoint == "" || bucket == "" {
+			return nil, fmt.Errorf("backup sink %s requires --%s and --%s", sinkType, settings.BackupS3EndpointOption, settings.BackupS3BucketOption)
+		}
+		return backup.NewS3Sink(endpoint, bucket, region, os.Getenv(s3AccessKeyIdEnvironmentVariable), os.Getenv(s3SecretAccessKeyEnvironmentVariable))
+	}
+	return nil, fmt.Errorf("invalid backup sink %q, allowed values: dir or s3", sinkType)
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/circuitbreaker"
+	"github.com/equinor/radix-cluster-cleanup/pkg/inactivity"
+	"github.com/equinor/radix-cluster-cleanup/pkg/settings"
+	"github.com/equinor/radix-operator/pkg/apis/kube"
+)
+
+// errNoCircuitBreakerConfigMap is returned when circuit breaker limits are set without a ConfigMap to keep its state in
+var errNoCircuitBreakerConfigMap = fmt.Errorf("--%s is required with circuit breaker limits, so a trip survives restarts and can be reset with acknowledge-circuit-breaker", settings.CircuitBreakerConfigMapOption)
+
+// getCircuitBreaker returns the circuit breaker with the stop and deletion limits from the options, keeping its state
+// in the ConfigMap given by the options, or nil if no ConfigMap or limits are given. Limits without a ConfigMap are an
+// error, as acknowledge-circuit-breaker runs in a separate process and could not reset a trip kept in memory.
+func getCircuitBreaker(kubeClient *kube.Kube) (*circuitbreaker.Breaker, error) {
+	stopLimits, err := getCircuitBreakerLimits(settings.MaxStopsPerRunOption, settings.MaxStopsPercentPerRunOption, settings.MaxStopsPerDayOption, settings.MaxStopsPercentPerDayOption)
+	if err != nil {
+		return nil, err
+	}
+	deletionLimits, err := getCircuitBreakerLimits(settings.MaxDeletionsPerRunOption, settings.MaxDeletionsPercentPerRunOption, settings.MaxDeletionsPerDayOption, settings.MaxDeletionsPercentPerDayOption)
+	if err != nil {
+		return nil, err
+	}
+	configMap, err := rootCmd.Flags().GetString(settings.CircuitBreakerConfigMapOption)
+	if err != nil {
+		return nil, err
+	}
+	if configMap == "" {
+		if stopLimits != (circuitbreaker.Limits{}) || deletionLimits != (circuitbreaker.Limits{}) {
+			return nil, errNoCircuitBreakerConfigMap
+		}
+		return nil, nil
+	}
+	namespace, name, ok := strings.Cut(configMap, "/")
+	if !ok {
+		return nil, fmt.Errorf("--%s must be on the form namespace/name", settings.CircuitBreakerConfigMapOption)
+	}
+	store := circuitbreaker.NewConfigMapStore(kubeClient.KubeClient(), namespace, name)
+	return circuitbreaker.New(store, map[string]circuitbreaker.Limits{
+		inactivity.ActionStop:     stopLimits,
+		inactivity.ActionDeletion: deletionLimits,
+	}, inactivity.RealClock{}), nil
+}
+
+func getCircuitBreakerLimits(perRunOption, percentPerRunOption, perDayOption, percentPerDayOption string) (circuitbreaker.Limits, error) {
+	perRun, perRunErr := rootCmd.Flags().GetInt(perRunOption)
+	percentPerRun, percentPerRunErr := rootCmd.Flags().GetFloat64(percentPerRunOption)
+	perDay, perDayErr := rootCmd.Flags().GetInt(perDayOption)
+	percentPerDay, percentPerDayErr := rootCmd.Flags().GetFloat64(percentPerDayOption)
+	if err := errors.Join(perRunErr, percentPerRunErr, perDayErr, percentPerDayErr); err != nil {
+		return circuitbreaker.Limits{}, err
+	}
+	return circuitbreaker.Limits{
+		MaxPerRun:        perRun,
+		MaxPercentPerRun: percentPerRun,
+		MaxPerDay:        perDay,
+		MaxPercentPerDay: percentPerDay,
+	}, nil
+}
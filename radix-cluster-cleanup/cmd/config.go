@@ -0,0 +1,141 @@
+// Copyright © 2022
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/config"
+	"github.com/equinor/radix-cluster-cleanup/pkg/settings"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+)
+
+// effectiveConfig holds the effective value and source of each option of the running command
+var effectiveConfig []config.Value
+
+var configCommand = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect configuration",
+	Long:  "Inspect configuration. Options are read from defaults, the --config YAML file, RX_CLEANUP_* environment variables and flags, each overriding the previous",
+}
+
+var configPrintCommand = &cobra.Command{
+	Use:   "print",
+	Short: "Print the effective value and source of every option",
+	Long:  "Print the effective value and source of every option: default, file, env or flag",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := cmd.Flags().GetString(settings.OutputOption)
+		if err != nil {
+			return err
+		}
+		return printConfig(effectiveConfig, format)
+	},
+}
+
+func init() {
+	configCommand.AddCommand(configPrintCommand)
+	rootCmd.AddCommand(configCommand)
+}
+
+// applyConfig sets the options of a command not given as flags from RX_CLEANUP_* environment variables, or the
+// config file given by --config or RX_CLEANUP_CONFIG
+func applyConfig(cmd *cobra.Command) ([]config.Value, error) {
+	path, err := cmd.Flags().GetString(settings.ConfigOption)
+	if err != nil {
+		return nil, err
+	}
+	if envPath, ok := os.LookupEnv(config.EnvName(settings.ConfigOption)); ok && !cmd.Flags().Changed(settings.ConfigOption) {
+		path = envPath
+	}
+	var fileValues map[string]string
+	if path != "" {
+		if fileValues, err = config.LoadFile(path); err != nil {
+			return nil, err
+		}
+		if err := validateConfigFile(cmd.Root(), path, fileValues); err != nil {
+			return nil, err
+		}
+	}
+	return config.Apply(cmd.Flags(), fileValues, path, os.LookupEnv, "help")
+}
+
+// validateConfigFile returns an error if the config file has keys which are not options of any command, so a misspelt
+// option is not silently ignored. Options of other commands are allowed, so one file can be shared between commands.
+func validateConfigFile(root *cobra.Command, path string, fileValues map[string]string) error {
+	options := allOptions(root)
+	var unknown []string
+	for name := range fileValues {
+		if name == settings.ConfigOption || !slices.Contains(options, name) {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown options in config file %s: %s", path, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+func allOptions(cmd *cobra.Command) []string {
+	var options []string
+	addOption := func(flag *pflag.Flag) { options = append(options, flag.Name) }
+	cmd.PersistentFlags().VisitAll(addOption)
+	cmd.LocalNonPersistentFlags().VisitAll(addOption)
+	for _, child := range cmd.Commands() {
+		options = append(options, allOptions(child)...)
+	}
+	return options
+}
+
+func printConfig(values []config.Value, format string) error {
+	switch format {
+	case outputJson:
+		out, err := json.MarshalIndent(values, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	case outputYaml:
+		out, err := yaml.Marshal(values)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(out))
+		return nil
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(writer, "NAME\tVALUE\tSOURCE"); err != nil {
+		return err
+	}
+	for _, value := range values {
+		source := string(value.Source)
+		if value.Origin != "" {
+			source = fmt.Sprintf("%s (%s)", source, value.Origin)
+		}
+		if _, err := fmt.Fprintf(writer, "%s\t%s\t%s\n", value.Name, valueOrDash(value.Value), source); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/settings"
+)
+
+func TestValidateConfigFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		fileValues map[string]string
+		unknown    string
+	}{
+		{name: "options of any command", fileValues: map[string]string{settings.InactiveDaysBeforeStopOption: "14", settings.AllowNextRunOption: "true"}},
+		{name: "unknown option", fileValues: map[string]string{settings.InactiveDaysBeforeStopOption: "14", "inactive-days-before-stopp": "14"}, unknown: "inactive-days-before-stopp"},
+		{name: "config option", fileValues: map[string]string{settings.ConfigOption: "other.yaml"}, unknown: settings.ConfigOption},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateConfigFile(rootCmd, "config.yaml", test.fileValues)
+			if test.unknown == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.unknown) {
+				t.Errorf("expected an error naming %s, got %v", test.unknown, err)
+			}
+		})
+	}
+}
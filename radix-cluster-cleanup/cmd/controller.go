@@ -0,0 +1,105 @@
+// Copyright © 2022
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/controller"
+	"github.com/equinor/radix-cluster-cleanup/pkg/inactivity"
+	"github.com/equinor/radix-cluster-cleanup/pkg/metrics"
+	"github.com/equinor/radix-cluster-cleanup/pkg/settings"
+	"github.com/spf13/cobra"
+)
+
+// serviceAccountNamespaceFile holds the namespace of the pod, when running in a cluster
+const serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+var controllerCommand = &cobra.Command{
+	Use:   "controller",
+	Short: "Stop and delete inactive RRs on changes to RRs, RDs and RJs, and periodically, in the elected leader",
+	Long: strings.TrimSpace(`
+Watch RadixRegistrations, RadixDeployments and RadixJobs, and stop and delete inactive RRs when they are created or
+changed, and every period, within the cleanup window.
+Replicas elect a leader with a Lease, and only the leader acts, so more than one replica can run, e.g. during a
+rollout. Another replica takes over when the leader stops.`),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runController(cmd.Context(), cmd)
+	},
+}
+
+func init() {
+	controllerCommand.Flags().String(settings.LeaseNamespaceOption, "", "namespace of the Lease the replicas elect a leader with. Defaults to the namespace of the pod")
+	controllerCommand.Flags().String(settings.LeaseNameOption, "radix-cluster-cleanup", "name of the Lease the replicas elect a leader with")
+	controllerCommand.Flags().Duration(settings.LeaseDurationOption, 15*time.Second, "how long other replicas wait before taking over from a leader which stopped renewing the Lease")
+	controllerCommand.Flags().Duration(settings.EventDebounceOption, time.Minute, "how long after a change to wait before acting, so a burst of changes leads to one run")
+	rootCmd.AddCommand(controllerCommand)
+}
+
+func runController(ctx context.Context, cmd *cobra.Command) error {
+	leaseNamespace, leaseNamespaceErr := cmd.Flags().GetString(settings.LeaseNamespaceOption)
+	leaseName, leaseNameErr := cmd.Flags().GetString(settings.LeaseNameOption)
+	leaseDuration, leaseDurationErr := cmd.Flags().GetDuration(settings.LeaseDurationOption)
+	debounce, debounceErr := cmd.Flags().GetDuration(settings.EventDebounceOption)
+	period, periodErr := rootCmd.Flags().GetDuration(settings.CleanUpPeriodOption)
+	if err := errors.Join(leaseNamespaceErr, leaseNameErr, leaseDurationErr, debounceErr, periodErr); err != nil {
+		return err
+	}
+	if leaseNamespace == "" {
+		namespace, err := os.ReadFile(serviceAccountNamespaceFile)
+		if err != nil {
+			return fmt.Errorf("--%s is required outside a cluster: %w", settings.LeaseNamespaceOption, err)
+		}
+		leaseNamespace = strings.TrimSpace(string(namespace))
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	window, err := getCleanupWindow()
+	if err != nil {
+		return err
+	}
+	if err := serveMetrics(ctx); err != nil {
+		return err
+	}
+
+	kubeClient, radixClient := getKubernetesClient()
+	cleanupController, err := controller.New(kubeClient, radixClient, controller.Options{
+		Reconcile: func(ctx context.Context, listResources inactivity.ListFunc) error {
+			start := time.Now()
+			err := stopAndDeleteInactiveRrsFrom(ctx, listResources)
+			metrics.ObserveRun(start, err)
+			return err
+		},
+		Window:         window,
+		Resync:         period,
+		Debounce:       debounce,
+		LeaseNamespace: leaseNamespace,
+		LeaseName:      leaseName,
+		Identity:       cmp.Or(os.Getenv("POD_NAME"), hostname),
+		LeaseDuration:  leaseDuration,
+	})
+	if err != nil {
+		return err
+	}
+	return cleanupController.Run(ctx)
+}
@@ -16,14 +16,11 @@ package cmd
 
 import (
 	"context"
-	"time"
 
-	"github.com/equinor/radix-cluster-cleanup/pkg/settings"
-	"github.com/equinor/radix-operator/pkg/apis/kube"
-	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	"github.com/equinor/radix-cluster-cleanup/pkg/cleanup"
+	"github.com/equinor/radix-cluster-cleanup/pkg/inactivity"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 var deleteRrsContinuouslyCommand = &cobra.Command{
@@ -50,34 +47,30 @@ func init() {
 }
 
 func deleteRrs(ctx context.Context) error {
+	return deleteRrsFrom(ctx, nil)
+}
+
+// deleteRrsFrom runs like deleteRrs, on the resources returned by listResources, or listed from the cluster if nil
+func deleteRrsFrom(ctx context.Context, listResources inactivity.ListFunc) error {
 	kubeClient, err := getKubeUtil()
 	if err != nil {
 		return err
 	}
-	action := "deletion"
-	inactiveDaysBeforeDeletion, err := rootCmd.Flags().GetInt64(settings.InactiveDaysBeforeDeletionOption)
+	options, err := getCleanupOptions(ctx, kubeClient, true)
 	if err != nil {
 		return err
 	}
-	inactivityBeforeDeletion := time.Hour * 24 * time.Duration(inactiveDaysBeforeDeletion)
-	tooInactiveRrs, err := getTooInactiveRrs(ctx, kubeClient, inactivityBeforeDeletion, action)
+	options.ListResources = listResources
+	cleaner, err := cleanup.New(kubeClient.KubeClient(), kubeClient.RadixClient(), options)
 	if err != nil {
 		return err
 	}
-	for _, rr := range tooInactiveRrs {
-		err := deleteRr(ctx, kubeClient, rr)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-func deleteRr(ctx context.Context, client *kube.Kube, rr v1.RadixRegistration) error {
-	err := client.RadixClient().RadixV1().RadixRegistrations().Delete(ctx, rr.Name, metav1.DeleteOptions{})
+	result, err := cleaner.Delete(ctx)
 	if err != nil {
 		return err
 	}
-	log.Info().Str("appName", rr.Name).Msg("Deleted RadixRegistration")
-	return nil
+	if result.Interrupted {
+		log.Ctx(ctx).Info().Msgf("deletion interrupted by shutdown, deleted %d of %d RadixRegistrations due for deletion", len(result.Deleted), len(result.Due))
+	}
+	return cleanup.Summarize(ctx, inactivity.ActionDeletion, result.Failures)
 }
@@ -0,0 +1,18 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/cleanup"
+	"github.com/equinor/radix-cluster-cleanup/pkg/inactivity"
+	"github.com/equinor/radix-cluster-cleanup/pkg/settings"
+)
+
+// getExemptRrs returns the exempt RadixRegistrations with the reason they are exempt, if the show-exempt option is set
+func getExemptRrs(ctx context.Context, cleaner *cleanup.Cleaner) ([]inactivity.Activity, error) {
+	showExempt, err := rootCmd.Flags().GetBool(settings.ShowExemptOption)
+	if err != nil || !showExempt {
+		return nil, err
+	}
+	return cleaner.Exempt(ctx)
+}
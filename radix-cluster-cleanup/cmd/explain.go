@@ -0,0 +1,276 @@
+// Copyright © 2022
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/cleanup"
+	"github.com/equinor/radix-cluster-cleanup/pkg/inactivity"
+	"github.com/equinor/radix-cluster-cleanup/pkg/settings"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	verdictExempt   = "exempt"
+	verdictSkipped  = "skipped"
+	verdictActive   = "active"
+	verdictWarning  = "warning"
+	verdictWarnOnly = "warn-only"
+	verdictDue      = "due"
+)
+
+var explainCommand = &cobra.Command{
+	Use:   "explain <app>",
+	Short: "Explain the stop and deletion verdicts for a RadixRegistration",
+	Long:  "Evaluate a single RadixRegistration the same way the stop and delete commands do, and print every signal considered, the exemptions and thresholds applied, and when each action will trigger if nothing changes",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return explain(cmd.Context(), args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(explainCommand)
+}
+
+// explanation is the full inactivity verdict for one RadixRegistration
+type explanation struct {
+	AppName   string             `json:"appName"`
+	Owner     string             `json:"owner,omitempty"`
+	RrCreated metav1.Time        `json:"rrCreated"`
+	Exemption string             `json:"exemption,omitempty"`
+	Verdicts  []explainedVerdict `json:"verdicts"`
+}
+
+// explainedVerdict is the verdict for one action on a RadixRegistration, or one of its environments for stop
+type explainedVerdict struct {
+	Action          string           `json:"action"`
+	Environment     string           `json:"environment,omitempty"`
+	Verdict         string           `json:"verdict"`
+	Reason          string           `json:"reason"`
+	Signals         []activitySignal `json:"signals,omitempty"`
+	LastActivity    *metav1.Time     `json:"lastActivity,omitempty"`
+	DaysInactive    int              `json:"daysInactive"`
+	Threshold       string           `json:"threshold,omitempty"`
+	ThresholdSource string           `json:"thresholdSource,omitempty"`
+	PolicyRule      string           `json:"policyRule,omitempty"`
+	MarkedAt        *metav1.Time     `json:"markedAt,omitempty"`
+	WarnAt          *metav1.Time     `json:"warnAt,omitempty"`
+	TriggersAt      *metav1.Time     `json:"triggersAt,omitempty"`
+}
+
+// activitySignal is a timestamp considered when finding the last activity, and the object it was read from
+type activitySignal struct {
+	Signal string      `json:"signal"`
+	Time   metav1.Time `json:"time"`
+	Source string      `json:"source"`
+}
+
+func explain(ctx context.Context, appName string) error {
+	kubeClient, err := getKubeUtil()
+	if err != nil {
+		return err
+	}
+	rr, err := kubeClient.RadixClient().RadixV1().RadixRegistrations().Get(ctx, appName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	cleaner, err := getCleaner(ctx, kubeClient, true)
+	if err != nil {
+		return err
+	}
+	evaluator := cleaner.Evaluator()
+	resources, err := inactivity.ListAppResources(ctx, kubeClient.RadixClient(), appName)
+	if err != nil {
+		return err
+	}
+	gracePeriod, err := rootCmd.Flags().GetDuration(settings.DeletionGracePeriodOption)
+	if err != nil {
+		return err
+	}
+	result := explanation{AppName: rr.Name, Owner: rr.Spec.Owner, RrCreated: rr.CreationTimestamp}
+	result.Exemption, _ = evaluator.Exemption(rr)
+
+	var errs []error
+	for _, action := range []string{inactivity.ActionStop, inactivity.ActionDeletion} {
+		threshold := cleaner.Threshold(action)
+		verdicts, failures := evaluator.Evaluate(ctx, resources, rr, threshold, action == inactivity.ActionStop)
+		for _, failure := range failures {
+			errs = append(errs, fmt.Errorf("failed to evaluate %s: %w", action, failure.Err))
+		}
+		for _, verdict := range verdicts {
+			result.Verdicts = append(result.Verdicts, explainVerdict(verdict, threshold, gracePeriod))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	return printExplanation(os.Stdout, result)
+}
+
+// explainVerdict describes the verdict for an action, and when the action will trigger if nothing changes
+func explainVerdict(verdict inactivity.Verdict, threshold inactivity.Threshold, gracePeriod time.Duration) explainedVerdict {
+	activity := verdict.Activity
+	explained := explainedVerdict{
+		Action:      threshold.Action,
+		Environment: activity.Environment,
+		PolicyRule:  activity.PolicyRule,
+	}
+	switch {
+	case verdict.Skipped != "":
+		explained.Verdict, explained.Reason = verdictSkipped, verdict.Skipped
+		return explained
+	case verdict.Exempt:
+		explained.Verdict, explained.Reason = verdictExempt, activity.Exemption
+		if verdict.LatestRd == nil {
+			return explained
+		}
+	}
+
+	explained.Signals = getActivitySignals(verdict)
+	explained.LastActivity = &activity.LastActivity
+	explained.DaysInactive = activity.DaysInactive
+	explained.Threshold = activity.Threshold
+	explained.ThresholdSource = activity.ThresholdSource
+	markedAt, marked := cleanup.GetMarkedTimestamp(verdict.InactiveRr, threshold.Action)
+	if marked {
+		explained.MarkedAt = &metav1.Time{Time: markedAt}
+	}
+	if verdict.Exempt {
+		return explained
+	}
+
+	dueAt := activity.LastActivity.Add(verdict.InactivityLimit)
+	if threshold.WarningPeriod > 0 {
+		explained.WarnAt = &metav1.Time{Time: dueAt.Add(-threshold.WarningPeriod)}
+	}
+	if !verdict.WarnOnly {
+		triggersAt := dueAt
+		if threshold.Action == inactivity.ActionDeletion {
+			if marked {
+				triggersAt = markedAt
+			}
+			triggersAt = triggersAt.Add(gracePeriod)
+		}
+		explained.TriggersAt = &metav1.Time{Time: triggersAt}
+	}
+
+	switch {
+	case verdict.WarnOnly:
+		explained.Verdict, explained.Reason = verdictWarnOnly, fmt.Sprintf("policy rule %s only warns owners", activity.PolicyRule)
+	case verdict.PastLimit:
+		explained.Verdict, explained.Reason = verdictDue, fmt.Sprintf("inactive for %d days, past the threshold of %s", activity.DaysInactive, activity.Threshold)
+	case verdict.TooInactive:
+		explained.Verdict, explained.Reason = verdictWarning, fmt.Sprintf("inactive for %d days, within the warning period before the threshold of %s", activity.DaysInactive, activity.Threshold)
+	default:
+		explained.Verdict, explained.Reason = verdictActive, fmt.Sprintf("inactive for %d days, within the threshold of %s", activity.DaysInactive, activity.Threshold)
+	}
+	return explained
+}
+
+// getActivitySignals returns the timestamps the last activity is the most recent of
+func getActivitySignals(verdict inactivity.Verdict) []activitySignal {
+	activity := verdict.Activity
+	signals := []activitySignal{{Signal: "RadixRegistration created", Time: activity.RrCreated, Source: "RadixRegistration " + activity.AppName}}
+	if rd := verdict.LatestRd; rd != nil {
+		source := fmt.Sprintf("RadixDeployment %s/%s", rd.Namespace, rd.Name)
+		signals = append(signals, activitySignal{Signal: "latest RadixDeployment active from", Time: rd.Status.ActiveFrom, Source: source})
+		if activity.LastUserMutation != nil {
+			signals = append(signals, activitySignal{Signal: "last user mutation", Time: *activity.LastUserMutation, Source: fmt.Sprintf("%s annotation %s", source, inactivity.LastUserMutationAnnotation)})
+		}
+	}
+	if rj := verdict.LatestRj; rj != nil && activity.LatestJobCreated != nil {
+		signals = append(signals, activitySignal{Signal: "latest RadixJob created", Time: *activity.LatestJobCreated, Source: fmt.Sprintf("RadixJob %s/%s", rj.Namespace, rj.Name)})
+	}
+	return signals
+}
+
+// printExplanation writes an explanation as JSON or YAML with the output option, otherwise as text
+func printExplanation(out io.Writer, result explanation) error {
+	format, err := rootCmd.Flags().GetString(settings.OutputOption)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case outputJson:
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(data))
+		return err
+	case outputYaml:
+		data, err := yaml.Marshal(result)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprint(out, string(data))
+		return err
+	}
+
+	tabWriter := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	writer := &errWriter{writer: tabWriter}
+	writer.printf("App:\t%s\n", result.AppName)
+	writer.printf("Owner:\t%s\n", valueOrDash(result.Owner))
+	writer.printf("RR created:\t%s\n", formatTime(&result.RrCreated))
+	writer.printf("Exemption:\t%s\n", valueOrDash(result.Exemption))
+	for _, verdict := range result.Verdicts {
+		subject := verdict.Action
+		if verdict.Environment != "" {
+			subject = fmt.Sprintf("%s of environment %s", verdict.Action, verdict.Environment)
+		}
+		writer.printf("\n%s:\t%s\n", subject, verdict.Verdict)
+		writer.printf("  Reason:\t%s\n", verdict.Reason)
+		if verdict.LastActivity == nil {
+			continue
+		}
+		for _, signal := range verdict.Signals {
+			writer.printf("  %s:\t%s\t%s\n", signal.Signal, formatTime(&signal.Time), signal.Source)
+		}
+		writer.printf("  Last activity:\t%s\t%d days ago\n", formatTime(verdict.LastActivity), verdict.DaysInactive)
+		writer.printf("  Threshold:\t%s\t%s\n", valueOrDash(verdict.Threshold), valueOrDash(verdict.ThresholdSource))
+		writer.printf("  Policy rule:\t%s\n", valueOrDash(verdict.PolicyRule))
+		writer.printf("  Marked:\t%s\n", formatTime(verdict.MarkedAt))
+		writer.printf("  Warns at:\t%s\n", formatTime(verdict.WarnAt))
+		writer.printf("  Triggers at:\t%s\n", formatTime(verdict.TriggersAt))
+	}
+	if writer.err != nil {
+		return writer.err
+	}
+	return tabWriter.Flush()
+}
+
+// errWriter writes formatted text until a write fails, and keeps the first error
+type errWriter struct {
+	writer io.Writer
+	err    error
+}
+
+func (w *errWriter) printf(format string, args ...any) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = fmt.Fprintf(w.writer, format, args...)
+}
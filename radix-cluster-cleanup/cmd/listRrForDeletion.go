@@ -16,10 +16,10 @@ package cmd
 
 import (
 	"context"
-	"fmt"
-	"time"
 
-	"github.com/equinor/radix-cluster-cleanup/pkg/settings"
+	"github.com/equinor/radix-cluster-cleanup/pkg/cleanup"
+	"github.com/equinor/radix-cluster-cleanup/pkg/inactivity"
+	"github.com/equinor/radix-cluster-cleanup/pkg/metrics"
 
 	"github.com/spf13/cobra"
 )
@@ -47,20 +47,24 @@ func listRrsForDeletion(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	action := "deletion"
-	inactiveDaysBeforeDeletion, err := rootCmd.Flags().GetInt64(settings.InactiveDaysBeforeDeletionOption)
+	action := inactivity.ActionDeletion
+	cleaner, err := getCleaner(ctx, kubeClient, false)
 	if err != nil {
 		return err
 	}
-	inactivityBeforeDeletion := time.Hour * 24 * time.Duration(inactiveDaysBeforeDeletion)
-	tooInactiveRrs, err := getTooInactiveRrs(ctx, kubeClient, inactivityBeforeDeletion, action)
+	result, err := cleaner.Evaluate(ctx, action)
 	if err != nil {
 		return err
 	}
-	for _, rr := range tooInactiveRrs {
-		fmt.Printf("%s\n", rr.Name)
+	metrics.SetRrsMarked(action, len(result.Due))
+	exemptRrs, err := getExemptRrs(ctx, cleaner)
+	if err != nil {
+		return err
+	}
+	if err := printInactiveRrs(result.Due, result.Warn, exemptRrs); err != nil {
+		return err
 	}
-	return nil
+	return cleanup.Summarize(ctx, action, result.Failures)
 }
 
 func init() {
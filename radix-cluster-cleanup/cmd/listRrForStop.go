@@ -16,18 +16,18 @@ package cmd
 
 import (
 	"context"
-	"fmt"
-	"time"
 
-	"github.com/equinor/radix-cluster-cleanup/pkg/settings"
+	"github.com/equinor/radix-cluster-cleanup/pkg/cleanup"
+	"github.com/equinor/radix-cluster-cleanup/pkg/inactivity"
+	"github.com/equinor/radix-cluster-cleanup/pkg/metrics"
 
 	"github.com/spf13/cobra"
 )
 
 var listRrsForStopContinuouslyCommand = &cobra.Command{
 	Use:   "list-rrs-for-stop-continuously",
-	Short: "Continuously list RadixRegistrations which qualify for stop",
-	Long:  "Continuously list RadixRegistrations which qualify for stop",
+	Short: "Continuously list environments of RadixRegistrations which qualify for stop",
+	Long:  "Continuously list environments of RadixRegistrations which qualify for stop",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return runFunctionPeriodically(cmd.Context(), listRrsForStop)
 	},
@@ -35,8 +35,8 @@ var listRrsForStopContinuouslyCommand = &cobra.Command{
 
 var listRrsForStopCommand = &cobra.Command{
 	Use:   "list-rrs-for-stop",
-	Short: "Lists RadixRegistrations which qualify for stop",
-	Long:  "Lists RadixRegistrations which qualify for stop.",
+	Short: "Lists environments of RadixRegistrations which qualify for stop",
+	Long:  "Lists environments of RadixRegistrations which qualify for stop.",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return listRrsForStop(cmd.Context())
 	},
@@ -47,20 +47,25 @@ func listRrsForStop(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	action := "stop"
-	inactiveDaysBeforeStop, err := rootCmd.Flags().GetInt64(settings.InactiveDaysBeforeStopOption)
+	action := inactivity.ActionStop
+	cleaner, err := getCleaner(ctx, kubeClient, false)
 	if err != nil {
 		return err
 	}
-	inactivityBeforeStop := time.Hour * 24 * time.Duration(inactiveDaysBeforeStop)
-	tooInactiveRrs, err := getTooInactiveRrs(ctx, kubeClient, inactivityBeforeStop, action)
+	result, err := cleaner.Evaluate(ctx, action)
 	if err != nil {
 		return err
 	}
-	for _, rr := range tooInactiveRrs {
-		fmt.Printf("%s\n", rr.Name)
+	metrics.SetRrsMarked(action, cleanup.CountApps(result.Due))
+	metrics.SetEnvironmentsMarked(action, len(result.Due))
+	exemptRrs, err := getExemptRrs(ctx, cleaner)
+	if err != nil {
+		return err
+	}
+	if err := printInactiveRrs(result.Due, result.Warn, exemptRrs); err != nil {
+		return err
 	}
-	return nil
+	return cleanup.Summarize(ctx, action, result.Failures)
 }
 
 func init() {
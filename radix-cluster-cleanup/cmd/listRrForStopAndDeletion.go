@@ -16,6 +16,7 @@ package cmd
 
 import (
 	"context"
+	"errors"
 
 	"github.com/spf13/cobra"
 )
@@ -30,11 +31,9 @@ var listRrsForStopAndDeletionContinuouslyCommand = &cobra.Command{
 }
 
 func listRrsForStopAndDeletion(ctx context.Context) error {
-	err := listRrsForStop(ctx)
-	if err != nil {
-		return err
-	}
-	return listRrsForDeletion(ctx)
+	stopErr := listRrsForStop(ctx)
+	deletionErr := listRrsForDeletion(ctx)
+	return errors.Join(stopErr, deletionErr)
 }
 
 func init() {
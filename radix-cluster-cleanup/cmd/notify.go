@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/notifier"
+	"github.com/equinor/radix-cluster-cleanup/pkg/settings"
+)
+
+const (
+	notifierSmtp    = "smtp"
+	notifierWebhook = "webhook"
+	notifierSlack   = "slack"
+	notifierTeams   = "teams"
+
+	smtpPasswordEnvironmentVariable = "SMTP_PASSWORD"
+)
+
+// getNotifier returns the notifier configured by the notifier option, or nil if none is configured
+func getNotifier() (notifier.Notifier, error) {
+	notifierType, err := rootCmd.Flags().GetString(settings.NotifierOption)
+	if err != nil {
+		return nil, err
+	}
+	switch notifierType {
+	case "":
+		return nil, nil
+	case notifierSmtp:
+		host, hostErr := rootCmd.Flags().GetString(settings.SmtpHostOption)
+		port, portErr := rootCmd.Flags().GetInt(settings.SmtpPortOption)
+		from, fromErr := rootCmd.Flags().GetString(settings.SmtpFromOption)
+		username, usernameErr := rootCmd.Flags().GetString(settings.SmtpUsernameOption)
+		if err := errors.Join(hostErr, portErr, fromErr, usernameErr); err != nil {
+			return nil, err
+		}
+		if host == "" || from == "" {
+			return nil, fmt.Errorf("notifier %s requires --%s and --%s", notifierType, settings.SmtpHostOption, settings.SmtpFromOption)
+		}
+		return notifier.NewSmtpNotifier(host, port, from, username, os.Getenv(smtpPasswordEnvironmentVariable)), nil
+	case notifierWebhook, notifierSlack, notifierTeams:
+		url, err := rootCmd.Flags().GetString(settings.NotifyWebhookUrlOption)
+		if err != nil {
+			return nil, err
+		}
+		if url == "" {
+			return nil, fmt.Errorf("notifier %s requires --%s", notifierType, settings.NotifyWebhookUrlOption)
+		}
+		if notifierType == notifierWebhook {
+			return notifier.NewWebhookNotifier(url), nil
+		}
+		return notifier.NewIncomingWebhookNotifier(url), nil
+	}
+	return nil, fmt.Errorf("invalid notifier %q, allowed values: smtp, webhook, slack or teams", notifierType)
+}
+
+// getWarningPeriod returns how long before the inactivity limit owners are warned, and the notifier to warn with.
+// A zero period means warnings are disabled.
+func getWarningPeriod(warnDaysOption string) (time.Duration, notifier.Notifier, error) {
+	warnDays, err := rootCmd.Flags().GetInt64(warnDaysOption)
+	if err != nil {
+		return 0, nil, err
+	}
+	if warnDays <= 0 {
+		return 0, nil, nil
+	}
+	n, err := getNotifier()
+	if err != nil {
+		return 0, nil, err
+	}
+	if n == nil {
+		return 0, nil, fmt.Errorf("--%s requires --%s", warnDaysOption, settings.NotifierOption)
+	}
+	return time.Hour * 24 * time.Duration(warnDays), n, nil
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/cleanup"
+	"github.com/equinor/radix-cluster-cleanup/pkg/inactivity"
+	"github.com/equinor/radix-cluster-cleanup/pkg/settings"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	outputName  = "name"
+	outputJson  = "json"
+	outputYaml  = "yaml"
+	outputTable = "table"
+	outputCsv   = "csv"
+)
+
+var outputFormats = []string{outputName, outputJson, outputYaml, outputTable, outputCsv}
+
+var activityColumns = []string{"APP", "ENVIRONMENT", "ACTION", "RR CREATED", "LATEST DEPLOYMENT", "DEPLOYMENT ACTIVE FROM", "LATEST JOB", "JOB CREATED", "LAST USER MUTATION", "LAST ACTIVITY", "DAYS INACTIVE", "THRESHOLD", "THRESHOLD SOURCE", "EXEMPTION"}
+
+func validateOutputFormat(format string) error {
+	if !slices.Contains(outputFormats, format) {
+		return fmt.Errorf("invalid output format %q, allowed values: name, json, yaml, table or csv", format)
+	}
+	return nil
+}
+
+// printInactiveRrs writes the inactive RadixRegistrations due for an action, those whose owners are only warned, with
+// the action warn, and the exempt RadixRegistrations to stdout in the format given by the output option
+func printInactiveRrs(dueRrs, warnRrs []inactivity.InactiveRr, exemptRrs []inactivity.Activity) error {
+	format, err := rootCmd.Flags().GetString(settings.OutputOption)
+	if err != nil {
+		return err
+	}
+	activities := make([]inactivity.Activity, 0, len(dueRrs)+len(warnRrs)+len(exemptRrs))
+	for _, inactiveRr := range dueRrs {
+		activities = append(activities, inactiveRr.Activity)
+	}
+	for _, inactiveRr := range warnRrs {
+		activity := inactiveRr.Activity
+		activity.Action = cleanup.ActionWarn
+		activities = append(activities, activity)
+	}
+	activities = append(activities, exemptRrs...)
+
+	switch format {
+	case outputName:
+		for _, activity := range activities {
+			if activity.Environment != "" {
+				fmt.Printf("%s/%s\n", activity.AppName, activity.Environment)
+				continue
+			}
+			fmt.Printf("%s\n", activity.AppName)
+		}
+		return nil
+	case outputJson:
+		out, err := json.MarshalIndent(activities, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	case outputYaml:
+		out, err := yaml.Marshal(activities)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(out))
+		return nil
+	case outputTable:
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		if _, err := fmt.Fprintln(writer, strings.Join(activityColumns, "\t")); err != nil {
+			return err
+		}
+		for _, activity := range activities {
+			if _, err := fmt.Fprintln(writer, strings.Join(activityRow(activity), "\t")); err != nil {
+				return err
+			}
+		}
+		return writer.Flush()
+	case outputCsv:
+		writer := csv.NewWriter(os.Stdout)
+		if err := writer.Write(activityColumns); err != nil {
+			return err
+		}
+		for _, activity := range activities {
+			if err := writer.Write(activityRow(activity)); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+	return validateOutputFormat(format)
+}
+
+func activityRow(activity inactivity.Activity) []string {
+	return []string{
+		activity.AppName,
+		valueOrDash(activity.Environment),
+		activity.Action,
+		formatTime(&activity.RrCreated),
+		valueOrDash(activity.LatestDeployment),
+		formatTime(activity.LatestDeploymentActiveFrom),
+		valueOrDash(activity.LatestJob),
+		formatTime(activity.LatestJobCreated),
+		formatTime(activity.LastUserMutation),
+		formatTime(&activity.LastActivity),
+		strconv.Itoa(activity.DaysInactive),
+		valueOrDash(activity.Threshold),
+		valueOrDash(activity.ThresholdSource),
+		valueOrDash(activity.Exemption),
+	}
+}
+
+func formatTime(timestamp *metav1.Time) string {
+	if timestamp == nil || timestamp.IsZero() {
+		return "-"
+	}
+	return timestamp.UTC().Format(time.RFC3339)
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
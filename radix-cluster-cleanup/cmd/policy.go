@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/inactivity"
+	"github.com/equinor/radix-cluster-cleanup/pkg/policy"
+	"github.com/equinor/radix-cluster-cleanup/pkg/settings"
+	"github.com/equinor/radix-operator/pkg/apis/kube"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const policyConfigMapKey = "policy.yaml"
+
+// getPolicy combines the rules of the policy file and ConfigMap, file rules first.
+// It is called at the start of every run, so changes to the file or ConfigMap apply without a restart.
+func getPolicy(ctx context.Context, kubeClient *kube.Kube) (*policy.Policy, error) {
+	policyFile, policyFileErr := rootCmd.Flags().GetString(settings.PolicyFileOption)
+	policyConfigMap, policyConfigMapErr := rootCmd.Flags().GetString(settings.PolicyConfigMapOption)
+	if err := errors.Join(policyFileErr, policyConfigMapErr); err != nil {
+		return nil, err
+	}
+	var rules []policy.Rule
+	if policyFile != "" {
+		data, err := os.ReadFile(policyFile)
+		if err != nil {
+			return nil, err
+		}
+		fileRules, err := policy.Parse(data, "file "+policyFile)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, fileRules...)
+	}
+	if policyConfigMap != "" {
+		namespace, name, ok := strings.Cut(policyConfigMap, "/")
+		if !ok {
+			return nil, fmt.Errorf("--%s must be on the form namespace/name", settings.PolicyConfigMapOption)
+		}
+		configMap, err := kubeClient.KubeClient().CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		configMapRules, err := policy.Parse([]byte(configMap.Data[policyConfigMapKey]), "configmap "+policyConfigMap)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, configMapRules...)
+	}
+	for _, rule := range rules {
+		if rule.Action != policy.ActionThreshold {
+			continue
+		}
+		if _, err := inactivity.ParseThreshold(rule.Threshold); err != nil {
+			return nil, fmt.Errorf("policy rule %s: %w", rule.Name, err)
+		}
+	}
+	return policy.New(rules...)
+}
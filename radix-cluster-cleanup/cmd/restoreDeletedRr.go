@@ -0,0 +1,190 @@
+// Copyright © 2022
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/settings"
+	"github.com/equinor/radix-cluster-cleanup/pkg/tombstone"
+	"github.com/equinor/radix-operator/pkg/apis/kube"
+	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	"github.com/equinor/radix-operator/pkg/apis/utils"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const appNamespaceTimeout = 2 * time.Minute
+
+var restoreDeletedRrCommand = &cobra.Command{
+	Use:   "restore-deleted-rr <app>",
+	Short: "Re-create a RadixRegistration deleted by the cleanup",
+	Long: strings.TrimSpace(`
+Re-create a RadixRegistration deleted by the cleanup from its tombstone, and list the secrets the owner must re-enter.
+Requires the --tombstone-dir or --tombstone-configmap the RadixRegistration was deleted with.
+The operator creates a new deploy key, which the owner must add to the GitHub repository before a pipeline job
+can clone it.`),
+	Args: cobra.MatchAll(cobra.ExactArgs(1), func(cmd *cobra.Command, args []string) error {
+		return tombstone.ValidateAppName(args[0])
+	}),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		triggerPipeline, triggerPipelineErr := cmd.Flags().GetBool(settings.TriggerPipelineOption)
+		pipelineImage, pipelineImageErr := cmd.Flags().GetString(settings.PipelineImageOption)
+		if err := errors.Join(triggerPipelineErr, pipelineImageErr); err != nil {
+			return err
+		}
+		return restoreDeletedRr(cmd.Context(), args[0], triggerPipeline, pipelineImage)
+	},
+}
+
+func init() {
+	restoreDeletedRrCommand.Flags().Bool(settings.TriggerPipelineOption, false, "trigger a build-deploy pipeline job for the config branch once the app namespace is created")
+	restoreDeletedRrCommand.Flags().String(settings.PipelineImageOption, "", "pipeline image for the pipeline job, e.g. radixprod.azurecr.io/radix-pipeline:release-latest. Defaults to the image of the latest build-deploy job recorded in the tombstone")
+	rootCmd.AddCommand(restoreDeletedRrCommand)
+}
+
+// restoreDeletedRr re-creates the RadixRegistration recorded in the tombstone of an application, optionally triggers
+// a pipeline job, and reports the secrets the owner must re-enter
+func restoreDeletedRr(ctx context.Context, appName string, triggerPipeline bool, pipelineImage string) error {
+	kubeClient, err := getKubeUtil()
+	if err != nil {
+		return err
+	}
+	store, err := getTombstoneStore(kubeClient)
+	if err != nil {
+		return err
+	}
+	if store == nil {
+		return fmt.Errorf("restoring a deleted RadixRegistration requires --%s or --%s", settings.TombstoneDirOption, settings.TombstoneConfigMapOption)
+	}
+	deleted, err := store.Get(ctx, appName)
+	if errors.Is(err, tombstone.ErrNotFound) {
+		return fmt.Errorf("no tombstone found for %s", appName)
+	}
+	if err != nil {
+		return err
+	}
+	var pipelineJob v1.RadixJobSpec
+	if triggerPipeline {
+		if pipelineJob, err = getPipelineJobSpec(deleted, pipelineImage); err != nil {
+			return err
+		}
+	}
+	logger := log.Ctx(ctx).With().Str("appName", appName).Logger()
+	ctx = logger.WithContext(ctx)
+	dryRun := isDryRun()
+
+	rr := &v1.RadixRegistration{
+		ObjectMeta: metav1.ObjectMeta{Name: deleted.AppName, Labels: deleted.Labels},
+		Spec:       deleted.RadixRegistration,
+	}
+	if _, err := kubeClient.RadixClient().RadixV1().RadixRegistrations().Create(ctx, rr, metav1.CreateOptions{DryRun: getDryRunOption()}); err != nil {
+		if kubeerrors.IsAlreadyExists(err) {
+			return fmt.Errorf("RadixRegistration %s already exists", appName)
+		}
+		return err
+	}
+	logger.Info().Bool("dryRun", dryRun).Msgf("re-created RadixRegistration deleted at %s", deleted.DeletedAt.Format(time.RFC3339))
+
+	if triggerPipeline {
+		if dryRun {
+			logger.Info().Msgf("dry-run: would trigger build-deploy pipeline job for branch %s", pipelineJob.Build.Branch)
+		} else if err := triggerBuildDeployJob(ctx, kubeClient, deleted.AppName, pipelineJob); err != nil {
+			return err
+		}
+	}
+	printRestoreReport(*deleted)
+	return nil
+}
+
+// getPipelineJobSpec returns the spec of the pipeline job triggered on restore: a copy of the latest build-deploy job
+// recorded in the tombstone, building the latest commit of its branch, or a build-deploy job for the config branch if
+// none was recorded. The pipeline image, if given, replaces the recorded one.
+func getPipelineJobSpec(deleted *tombstone.Tombstone, pipelineImage string) (v1.RadixJobSpec, error) {
+	spec := v1.RadixJobSpec{
+		PipeLineType: v1.BuildDeploy,
+		Build:        v1.RadixBuildSpec{Branch: deleted.RadixRegistration.ConfigBranch},
+	}
+	if deleted.RadixJob != nil {
+		spec = *deleted.RadixJob.DeepCopy()
+		spec.Build.CommitID = ""
+	}
+	spec.AppName = deleted.AppName
+	spec.CloneURL = deleted.RadixRegistration.CloneURL
+	spec.PipelineImage = cmp.Or(pipelineImage, spec.PipelineImage)
+	spec.TriggeredBy = "radix-cluster-cleanup"
+	if spec.PipelineImage == "" {
+		return spec, fmt.Errorf("--%s requires --%s, as the tombstone has no build-deploy job to take it from", settings.TriggerPipelineOption, settings.PipelineImageOption)
+	}
+	return spec, nil
+}
+
+// triggerBuildDeployJob creates a pipeline RadixJob with the given spec, once the operator has created the app
+// namespace
+func triggerBuildDeployJob(ctx context.Context, kubeClient *kube.Kube, appName string, spec v1.RadixJobSpec) error {
+	namespace := utils.GetAppNamespace(appName)
+	err := wait.PollUntilContextTimeout(ctx, 5*time.Second, appNamespaceTimeout, true, func(ctx context.Context) (bool, error) {
+		_, err := kubeClient.KubeClient().CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+		if kubeerrors.IsNotFound(err) {
+			return false, nil
+		}
+		return err == nil, err
+	})
+	if err != nil {
+		return fmt.Errorf("app namespace %s was not created: %w", namespace, err)
+	}
+	rj := &v1.RadixJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("radix-pipeline-%s-%s", time.Now().UTC().Format("20060102150405"), utilrand.String(5)),
+			Namespace: namespace,
+		},
+		Spec: spec,
+	}
+	created, err := kubeClient.RadixClient().RadixV1().RadixJobs(namespace).Create(ctx, rj, metav1.CreateOptions{})
+	if err != nil {
+		return err
+	}
+	log.Ctx(ctx).Info().Msgf("triggered %s pipeline job %s for branch %s", spec.PipeLineType, created.Name, spec.Build.Branch)
+	return nil
+}
+
+// printRestoreReport writes what the owner must do to complete the restore to stdout
+func printRestoreReport(deleted tombstone.Tombstone) {
+	fmt.Printf("Re-created RadixRegistration %s, deleted by the cleanup at %s\n", deleted.AppName, deleted.DeletedAt.Format(time.RFC3339))
+	fmt.Printf("The owner %s must:\n", valueOrDash(deleted.RadixRegistration.Owner))
+	fmt.Printf("- add the new deploy key from the Radix console to %s\n", deleted.RadixRegistration.CloneURL)
+	fmt.Printf("- update the shared secret of the GitHub webhook from the Radix console\n")
+	if deleted.RadixApplication == nil {
+		fmt.Printf("- re-enter all secrets, the RadixApplication was not recorded\n")
+		return
+	}
+	secrets := deleted.Secrets()
+	if len(secrets) == 0 {
+		return
+	}
+	fmt.Printf("- re-enter the secrets in every environment:\n")
+	for _, secret := range secrets {
+		fmt.Printf("  %s: %s\n", secret.Component, secret.Name)
+	}
+}
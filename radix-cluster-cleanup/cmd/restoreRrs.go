@@ -0,0 +1,77 @@
+// Copyright © 2022
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/settings"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var restoreRrsCommand = &cobra.Command{
+	Use:   "restore-rrs",
+	Short: "Restore components stopped by the cleanup",
+	Long: strings.TrimSpace(`
+Restore the replicas of components stopped by the cleanup to the values they had before they were stopped.
+Components which have been started or scaled since they were stopped are left as they are.
+Restoring sets the last-user-mutation annotation on the RadixDeployment, so the environment is not stopped again
+until it has been inactive for the full inactivity period.`),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		appName, appErr := cmd.Flags().GetString(settings.RestoreAppOption)
+		stoppedAfter, stoppedAfterErr := cmd.Flags().GetString(settings.RestoreStoppedAfterOption)
+		if err := errors.Join(appErr, stoppedAfterErr); err != nil {
+			return err
+		}
+		var stoppedAfterTime time.Time
+		if stoppedAfter != "" {
+			var err error
+			if stoppedAfterTime, err = time.Parse(time.RFC3339, stoppedAfter); err != nil {
+				return err
+			}
+		}
+		return restoreRrs(cmd.Context(), appName, stoppedAfterTime)
+	},
+}
+
+func init() {
+	restoreRrsCommand.Flags().String(settings.RestoreAppOption, "", "restore the named application")
+	restoreRrsCommand.Flags().String(settings.RestoreStoppedAfterOption, "", "restore applications stopped after this RFC3339 timestamp. Deployments without a record of when they were stopped are skipped")
+	restoreRrsCommand.Flags().Bool(settings.RestoreAllOption, false, "restore all applications stopped by the cleanup")
+	restoreRrsCommand.MarkFlagsOneRequired(settings.RestoreAppOption, settings.RestoreStoppedAfterOption, settings.RestoreAllOption)
+	restoreRrsCommand.MarkFlagsMutuallyExclusive(settings.RestoreAppOption, settings.RestoreAllOption)
+	restoreRrsCommand.MarkFlagsMutuallyExclusive(settings.RestoreStoppedAfterOption, settings.RestoreAllOption)
+	rootCmd.AddCommand(restoreRrsCommand)
+}
+
+// restoreRrs restores the active RadixDeployments stopped by the cleanup, optionally limited to one application
+// and to those stopped after a point in time
+func restoreRrs(ctx context.Context, appName string, stoppedAfter time.Time) error {
+	kubeClient, err := getKubeUtil()
+	if err != nil {
+		return err
+	}
+	cleaner, err := getCleaner(ctx, kubeClient, false)
+	if err != nil {
+		return err
+	}
+	restored, err := cleaner.Restore(ctx, appName, stoppedAfter)
+	log.Ctx(ctx).Info().Msgf("restored %d RadixDeployments", len(restored))
+	return err
+}
@@ -1,30 +1,33 @@
 package cmd
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"math/rand"
 	"os"
-	"sort"
 	"strings"
 	"time"
 
+	"github.com/equinor/radix-cluster-cleanup/pkg/cleanup"
+	"github.com/equinor/radix-cluster-cleanup/pkg/inactivity"
+	"github.com/equinor/radix-cluster-cleanup/pkg/metrics"
+	"github.com/equinor/radix-cluster-cleanup/pkg/notifier"
 	"github.com/equinor/radix-cluster-cleanup/pkg/settings"
+	"github.com/equinor/radix-cluster-cleanup/pkg/whitelist"
 	"github.com/equinor/radix-common/utils/delaytick"
 	"github.com/equinor/radix-common/utils/timewindow"
 	"github.com/equinor/radix-operator/pkg/apis/kube"
-	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
-	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 
-	"github.com/equinor/radix-operator/pkg/apis/utils"
 	radixclient "github.com/equinor/radix-operator/pkg/client/clientset/versioned"
 )
 
@@ -41,6 +44,12 @@ var rootCmd = &cobra.Command{
 	Short: "Command line interface for cleaning up inactive RadixRegistrations",
 	Long:  rootLongHelp,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		values, err := applyConfig(cmd)
+		if err != nil {
+			return err
+		}
+		effectiveConfig = values
+
 		logLevel, err := cmd.Flags().GetString(settings.LogLevel)
 		if err != nil {
 			return err
@@ -51,6 +60,14 @@ var rootCmd = &cobra.Command{
 			return err
 		}
 
+		output, err := cmd.Flags().GetString(settings.OutputOption)
+		if err != nil {
+			return err
+		}
+		if err := validateOutputFormat(output); err != nil {
+			return err
+		}
+
 		return initZeroLogger(logLevel, prettyPrint)
 	},
 }
@@ -68,12 +85,53 @@ func Execute(ctx context.Context) {
 func init() {
 	rootCmd.PersistentFlags().Int64(settings.InactiveDaysBeforeDeletionOption, defaultInactiveDaysBeforeDeletion, "max inactivity period before deleting RadixRegistrations")
 	rootCmd.PersistentFlags().Int64(settings.InactiveDaysBeforeStopOption, defaultInactiveDaysBeforeStop, "max inactivity period before stopping components in RadixRegistrations")
+	rootCmd.PersistentFlags().Int64(settings.MaxInactiveDaysBeforeDeletionOption, 0, "max inactivity period the radix.equinor.com/cleanup-delete-after annotation on a RadixRegistration can extend the deletion threshold to. Less than inactive-days-before-deletion means annotations can only shorten it")
+	rootCmd.PersistentFlags().Int64(settings.MaxInactiveDaysBeforeStopOption, 0, "max inactivity period the radix.equinor.com/cleanup-stop-after annotation on a RadixRegistration can extend the stop threshold to. Less than inactive-days-before-stop means annotations can only shorten it")
+	rootCmd.PersistentFlags().Duration(settings.DeletionGracePeriodOption, 0, "how long a RadixRegistration must have been marked for deletion before it is deleted")
+	rootCmd.PersistentFlags().Int64(settings.WarnDaysBeforeStopOption, 0, "warn owners this many days before components in RadixRegistrations are stopped. 0 disables warnings")
+	rootCmd.PersistentFlags().Int64(settings.WarnDaysBeforeDeletionOption, 0, "warn owners this many days before RadixRegistrations are deleted. 0 disables warnings")
+	rootCmd.PersistentFlags().String(settings.NotifierOption, "", "how owners are warned before stop or deletion, allowed values: smtp, webhook, slack or teams")
+	rootCmd.PersistentFlags().String(settings.NotifyWebhookUrlOption, "", "URL to post warnings to for the webhook, slack and teams notifiers")
+	rootCmd.PersistentFlags().String(settings.SmtpHostOption, "", "SMTP server for the smtp notifier")
+	rootCmd.PersistentFlags().Int(settings.SmtpPortOption, 587, "SMTP server port for the smtp notifier")
+	rootCmd.PersistentFlags().String(settings.SmtpFromOption, "", "sender address for the smtp notifier")
+	rootCmd.PersistentFlags().String(settings.SmtpUsernameOption, "", "SMTP username for the smtp notifier. The password is read from the SMTP_PASSWORD environment variable")
 	rootCmd.PersistentFlags().String(settings.WhitelistOption, "", "custom whitelist of RadixRegistrations to exclude from cleanup. Appended to default, hardcoded whitelist")
+	rootCmd.PersistentFlags().String(settings.WhitelistFileOption, "", "YAML file with whitelist entries, appended to default, hardcoded whitelist. Re-read on every run")
+	rootCmd.PersistentFlags().String(settings.WhitelistConfigMapOption, "", "namespace/name of a ConfigMap with whitelist entries in the whitelist.yaml key, appended to default, hardcoded whitelist. Re-read on every run")
+	rootCmd.PersistentFlags().String(settings.PolicyFileOption, "", "YAML file with an ordered list of policy rules, each mapping a CEL expression to an action: exempt, warn, stop, delete or threshold. Re-read on every run")
+	rootCmd.PersistentFlags().String(settings.PolicyConfigMapOption, "", "namespace/name of a ConfigMap with policy rules in the policy.yaml key, evaluated after rules in the policy file. Re-read on every run")
 	rootCmd.PersistentFlags().StringSlice(settings.CleanUpDaysOption, []string{"mo", "tu", "we", "th", "fr", "sa", "su"}, "for commands that run continuously, this option specifies which weekdays the command will be active")
 	rootCmd.PersistentFlags().String(settings.CleanUpStartOption, "06:00", "for commands that run continuously, this option specifies which time of day the command will be active from")
 	rootCmd.PersistentFlags().String(settings.CleanUpEndOption, "09:00", "for commands that run continuously, this option specifies which time of day the command will be active to")
 	rootCmd.PersistentFlags().Duration(settings.CleanUpPeriodOption, time.Minute*30, "for commands that run continuously, this option specifies how long between each consecutive run of the command")
-
+	rootCmd.PersistentFlags().Int(settings.MaxStopsPerRunOption, 0, "max number of environments stopped in one run before the circuit breaker trips. 0 disables the limit")
+	rootCmd.PersistentFlags().Float64(settings.MaxStopsPercentPerRunOption, 0, "max percentage of running environments stopped in one run before the circuit breaker trips. 0 disables the limit")
+	rootCmd.PersistentFlags().Int(settings.MaxStopsPerDayOption, 0, "max number of environments stopped within 24 hours before the circuit breaker trips. 0 disables the limit")
+	rootCmd.PersistentFlags().Float64(settings.MaxStopsPercentPerDayOption, 0, "max percentage of running environments stopped within 24 hours before the circuit breaker trips. 0 disables the limit")
+	rootCmd.PersistentFlags().Int(settings.MaxDeletionsPerRunOption, 0, "max number of RadixRegistrations deleted in one run before the circuit breaker trips. 0 disables the limit")
+	rootCmd.PersistentFlags().Float64(settings.MaxDeletionsPercentPerRunOption, 0, "max percentage of RadixRegistrations deleted in one run before the circuit breaker trips. 0 disables the limit")
+	rootCmd.PersistentFlags().Int(settings.MaxDeletionsPerDayOption, 0, "max number of RadixRegistrations deleted within 24 hours before the circuit breaker trips. 0 disables the limit")
+	rootCmd.PersistentFlags().Float64(settings.MaxDeletionsPercentPerDayOption, 0, "max percentage of RadixRegistrations deleted within 24 hours before the circuit breaker trips. 0 disables the limit")
+	rootCmd.PersistentFlags().String(settings.CircuitBreakerConfigMapOption, "", "namespace/name of a ConfigMap to keep the circuit breaker state in. Required with circuit breaker limits")
+	rootCmd.PersistentFlags().String(settings.BackupSinkOption, "", "where RadixRegistrations and related resources are backed up before deletion, allowed values: dir or s3. Deletion is refused if the backup fails")
+	rootCmd.PersistentFlags().String(settings.BackupDirOption, "", "directory, e.g. on a persistent volume, for the dir backup sink")
+	rootCmd.PersistentFlags().String(settings.BackupS3EndpointOption, "", "URL of the S3-compatible store for the s3 backup sink. Credentials are read from the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables")
+	rootCmd.PersistentFlags().String(settings.BackupS3BucketOption, "", "bucket for the s3 backup sink")
+	rootCmd.PersistentFlags().String(settings.BackupS3RegionOption, "us-east-1", "region for the s3 backup sink")
+	rootCmd.PersistentFlags().Bool(settings.NoBackupOption, false, "delete RadixRegistrations without a backup when no backup sink is configured. Deletion is refused without one or the other")
+	rootCmd.PersistentFlags().String(settings.TombstoneDirOption, "", "directory to keep a tombstone of each deleted RadixRegistration in, for restore-deleted-rr")
+	rootCmd.PersistentFlags().String(settings.TombstoneConfigMapOption, "", "namespace/name of a ConfigMap to keep a tombstone of each deleted RadixRegistration in, for restore-deleted-rr")
+	rootCmd.PersistentFlags().Int(settings.ConcurrencyOption, 1, "how many applications are evaluated and stopped at the same time")
+	rootCmd.PersistentFlags().Float32(settings.KubeApiQpsOption, rest.DefaultQPS, "max queries per second to the Kubernetes API server")
+	rootCmd.PersistentFlags().Int(settings.KubeApiBurstOption, rest.DefaultBurst, "max burst of queries to the Kubernetes API server")
+	rootCmd.PersistentFlags().Bool(settings.DryRunOption, false, "log every change stop and delete commands would make, and submit them as server-side dry-run requests without persisting them")
+	rootCmd.PersistentFlags().Bool(settings.ShowExemptOption, false, "for commands listing RadixRegistrations, also list exempt RadixRegistrations and why they are exempt")
+	rootCmd.PersistentFlags().StringP(settings.OutputOption, "o", outputName, "output format for commands listing RadixRegistrations, allowed values: name, json, yaml, table or csv")
+	rootCmd.PersistentFlags().Int(settings.MetricsPortOption, 8080, "for commands that run continuously, this option specifies which port the /metrics endpoint is served on")
+	rootCmd.PersistentFlags().Duration(settings.ShutdownGracePeriodOption, 20*time.Second, "for commands that run continuously, this option specifies how long a run may take to finish the application in progress when the command is terminated")
+
+	rootCmd.PersistentFlags().String(settings.ConfigOption, "", "YAML file with option values keyed by option name. Options are read from defaults, this file, RX_CLEANUP_* environment variables, e.g. RX_CLEANUP_INACTIVE_DAYS_BEFORE_STOP, and flags, each overriding the previous")
 	rootCmd.PersistentFlags().Bool(settings.PrettyPrint, false, "Enable colored log output instead of json")
 	rootCmd.PersistentFlags().String(settings.LogLevel, "info", "Set output log level, allowed values: debug, info, warn, error or fatal")
 }
@@ -96,7 +154,11 @@ func initZeroLogger(logLevel string, prettyPrint bool) error {
 	return nil
 }
 
-func getWhitelist() []string {
+const whitelistConfigMapKey = "whitelist.yaml"
+
+// getWhitelist combines the hardcoded whitelist with the whitelist option, file and ConfigMap.
+// It is called at the start of every run, so changes to the file or ConfigMap apply without a restart.
+func getWhitelist(ctx context.Context, kubeClient *kube.Kube) (*whitelist.Whitelist, error) {
 	hardcodedWhitelist := []string{
 		"radix-api",
 		"radix-public-site",
@@ -114,22 +176,80 @@ func getWhitelist() []string {
 		"canarycicd-test3",
 		"canarycicd-test4",
 	}
-	argWhitelist, _ := rootCmd.Flags().GetString(settings.WhitelistOption)
-	whitelist := append(hardcodedWhitelist, strings.Split(argWhitelist, ",")...)
-	return whitelist
-}
+	rrWhitelist, err := whitelist.New()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range hardcodedWhitelist {
+		if err := rrWhitelist.Add(whitelist.Entry{Name: name, Source: "hardcoded whitelist"}); err != nil {
+			return nil, err
+		}
+	}
 
-func getKubernetesClient() (kubernetes.Interface, radixclient.Interface) {
-	kubeConfigPath := os.Getenv("HOME") + "/.kube/config"
-	config, err := clientcmd.BuildConfigFromFlags("", kubeConfigPath)
+	argWhitelist, argWhitelistErr := rootCmd.Flags().GetString(settings.WhitelistOption)
+	whitelistFile, whitelistFileErr := rootCmd.Flags().GetString(settings.WhitelistFileOption)
+	whitelistConfigMap, whitelistConfigMapErr := rootCmd.Flags().GetString(settings.WhitelistConfigMapOption)
+	if err := errors.Join(argWhitelistErr, whitelistFileErr, whitelistConfigMapErr); err != nil {
+		return nil, err
+	}
+	for _, name := range strings.Split(argWhitelist, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if err := rrWhitelist.Add(whitelist.Entry{Name: name, Source: "--" + settings.WhitelistOption}); err != nil {
+			return nil, err
+		}
+	}
 
-	if err != nil {
-		config, err = rest.InClusterConfig()
+	if whitelistFile != "" {
+		data, err := os.ReadFile(whitelistFile)
 		if err != nil {
-			log.Fatal().Err(err).Msg("getClusterConfig InClusterConfig")
+			return nil, err
+		}
+		entries, err := whitelist.Parse(data, "file "+whitelistFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := rrWhitelist.Add(entries...); err != nil {
+			return nil, err
 		}
 	}
 
+	if whitelistConfigMap != "" {
+		namespace, name, ok := strings.Cut(whitelistConfigMap, "/")
+		if !ok {
+			return nil, fmt.Errorf("--%s must be on the form namespace/name", settings.WhitelistConfigMapOption)
+		}
+		configMap, err := kubeClient.KubeClient().CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		entries, err := whitelist.Parse([]byte(configMap.Data[whitelistConfigMapKey]), "configmap "+whitelistConfigMap)
+		if err != nil {
+			return nil, err
+		}
+		if err := rrWhitelist.Add(entries...); err != nil {
+			return nil, err
+		}
+	}
+	return rrWhitelist, nil
+}
+
+func isDryRun() bool {
+	dryRun, _ := rootCmd.Flags().GetBool(settings.DryRunOption)
+	return dryRun
+}
+
+// getDryRunOption returns the DryRun value for create, update, patch and delete options
+func getDryRunOption() []string {
+	if isDryRun() {
+		return []string{metav1.DryRunAll}
+	}
+	return nil
+}
+
+func getKubernetesClient() (kubernetes.Interface, radixclient.Interface) {
+	config := getClusterConfig()
 	client, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		log.Fatal().Err(err).Msg("getClusterConfig k8s client")
@@ -144,6 +264,36 @@ func getKubernetesClient() (kubernetes.Interface, radixclient.Interface) {
 	return client, radixClient
 }
 
+// getMetadataClient returns a client for the metadata of Kubernetes objects, without their spec, data or status
+func getMetadataClient() metadata.Interface {
+	metadataClient, err := metadata.NewForConfig(getClusterConfig())
+	if err != nil {
+		log.Fatal().Err(err).Msg("getClusterConfig metadata client")
+	}
+	return metadataClient
+}
+
+func getClusterConfig() *rest.Config {
+	kubeConfigPath := os.Getenv("HOME") + "/.kube/config"
+	config, err := clientcmd.BuildConfigFromFlags("", kubeConfigPath)
+
+	if err != nil {
+		config, err = rest.InClusterConfig()
+		if err != nil {
+			log.Fatal().Err(err).Msg("getClusterConfig InClusterConfig")
+		}
+	}
+
+	qps, qpsErr := rootCmd.Flags().GetFloat32(settings.KubeApiQpsOption)
+	burst, burstErr := rootCmd.Flags().GetInt(settings.KubeApiBurstOption)
+	if err := errors.Join(qpsErr, burstErr); err != nil {
+		log.Fatal().Err(err).Msg("getClusterConfig rate limits")
+	}
+	config.QPS = qps
+	config.Burst = burst
+	return config
+}
+
 func getKubeUtil() (*kube.Kube, error) {
 	kubeClient, radixClient := getKubernetesClient()
 	kubeutil, err := kube.New(kubeClient, radixClient, nil, nil)
@@ -153,227 +303,148 @@ func getKubeUtil() (*kube.Kube, error) {
 	return kubeutil, nil
 }
 
+// runFunctionPeriodically runs someFunc every period within the cleanup window until the context is cancelled. A run in
+// progress then gets the shutdown grace period to finish the application in progress, see cleanup.WithGracePeriod.
 func runFunctionPeriodically(ctx context.Context, someFunc func(ctx context.Context) error) error {
 	logger := log.Ctx(ctx)
-	cleanupDays, cleanupDaysErr := rootCmd.Flags().GetStringSlice(settings.CleanUpDaysOption)
-	cleanupStart, cleanupStartErr := rootCmd.Flags().GetString(settings.CleanUpStartOption)
-	cleanupEnd, cleanupEndErr := rootCmd.Flags().GetString(settings.CleanUpEndOption)
 	period, periodErr := rootCmd.Flags().GetDuration(settings.CleanUpPeriodOption)
-	err := errors.Join(cleanupDaysErr, cleanupStartErr, cleanupEndErr, periodErr)
-	if err != nil {
+	gracePeriod, gracePeriodErr := rootCmd.Flags().GetDuration(settings.ShutdownGracePeriodOption)
+	if err := errors.Join(periodErr, gracePeriodErr); err != nil {
 		return err
 	}
-	timezone := "Local"
-	window, err := timewindow.New(cleanupDays, cleanupStart, cleanupEnd, timezone)
+	if err := serveMetrics(ctx); err != nil {
+		return err
+	}
+	window, err := getCleanupWindow()
 	if err != nil {
 		logger.Fatal().Err(err).Msg("Failed to build time window")
 	}
 	source := rand.NewSource(time.Now().UnixNano())
 	tick := delaytick.New(source, period)
-	for range tick {
-		pointInTime := time.Now()
-		if window.Contains(pointInTime) {
-			logger.Info().Msgf("Start listing RRs for stop %s", pointInTime)
-			err := someFunc(ctx)
-			if err != nil {
-				return err
-			}
-		} else {
-			logger.Info().Msgf("%s is outside of window. Continue sleeping", pointInTime)
+	runs, failedRuns := 0, 0
+	for {
+		select {
+		case <-ctx.Done():
+		case <-tick:
 		}
-	}
-	logger.Warn().Msgf("execution reached code which was presumably after an inescapable loop")
-	return nil
-}
-
-func getTooInactiveRrs(ctx context.Context, kubeClient *kube.Kube, inactivityLimit time.Duration, action string) ([]v1.RadixRegistration, error) {
-	rrs, err := kubeClient.ListRegistrations(ctx)
-	if err != nil {
-		return nil, err
-	}
-	var rrsForDeletion []v1.RadixRegistration
-	for _, rr := range rrs {
-		logger := log.Ctx(ctx).With().Str("appName", rr.Name).Logger()
-		ctx := logger.WithContext(ctx)
-
-		if isWhitelisted(rr) {
-			logger.Debug().Msg("RadixRegistration is whitelisted, skipping")
-			continue
+		if ctx.Err() != nil {
+			logger.Info().Msgf("Shutting down after %d runs, %d of them failed", runs, failedRuns)
+			return nil
 		}
-		ra, err := getRadixApplication(ctx, kubeClient, rr.Name)
-		if kubeerrors.IsNotFound(err) {
-			logger.Debug().Msg("could not find RadixApplication, continuing...")
+		pointInTime := time.Now()
+		if !window.Contains(pointInTime) {
+			logger.Info().Msgf("%s is outside of window. Continue sleeping", pointInTime)
 			continue
 		}
+		logger.Info().Msgf("Start listing RRs for stop %s", pointInTime)
+		runCtx, cancel := cleanup.WithGracePeriod(ctx, gracePeriod)
+		err := someFunc(runCtx)
+		cancel()
+		metrics.ObserveRun(pointInTime, err)
+		runs++
 		if err != nil {
-			return nil, err
-		}
-		namespaces := getRuntimeNamespaces(ra)
-		logger.Debug().Msgf("found namespaces %s associated with RadixRegistration", strings.Join(namespaces, ", "))
-		rdsForRr, err := getRadixDeploymentsInNamespaces(ctx, kubeClient, namespaces)
-		if err != nil {
-			return nil, err
-		}
-		logger.Debug().Msgf("RadixRegistration has %d RadixDeployments", len(rdsForRr))
-		rjsForRr, err := getRadixJobsInNamespace(ctx, kubeClient, utils.GetAppNamespace(rr.Name))
-		logger.Debug().Msgf("RadixRegistration has %d RadixJobs", len(rdsForRr))
-		if err != nil {
-			return nil, err
-		}
-
-		logger.Debug().Msg("Checking timestamps of RadixDeployments and RadixJobs")
-		isInactive, err := rrIsInactive(ctx, rr.CreationTimestamp, rdsForRr, rjsForRr, inactivityLimit, action)
-		if err != nil {
-			return nil, err
-		}
-		if isInactive {
-			rrsForDeletion = append(rrsForDeletion, *rr)
+			failedRuns++
+			logger.Error().Err(err).Msg("Run failed, retrying in the next period")
 		}
 	}
-	return rrsForDeletion, nil
 }
 
-func getRadixJobsInNamespace(ctx context.Context, kubeClient *kube.Kube, namespace string) ([]v1.RadixJob, error) {
-	rjs, err := kubeClient.RadixClient().RadixV1().RadixJobs(namespace).List(ctx, metav1.ListOptions{})
+// serveMetrics serves the /metrics endpoint in the background, for commands that run continuously
+func serveMetrics(ctx context.Context) error {
+	metricsPort, err := rootCmd.Flags().GetInt(settings.MetricsPortOption)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return rjs.Items, nil
+	go func() {
+		if err := metrics.Serve(ctx, metricsPort); err != nil {
+			log.Ctx(ctx).Error().Err(err).Msg("Failed to serve metrics")
+		}
+	}()
+	return nil
 }
 
-func getRadixDeploymentsInNamespaces(ctx context.Context, kubeClient *kube.Kube, namespaces []string) ([]v1.RadixDeployment, error) {
-	rdsForRr := make([]v1.RadixDeployment, 0)
-	for _, ns := range namespaces {
-		rds, err := kubeClient.RadixClient().RadixV1().RadixDeployments(ns).List(ctx, metav1.ListOptions{})
-		if err != nil {
-			return nil, err
-		}
-		rdsForRr = append(rdsForRr, rds.Items...)
+// getCleanupWindow returns the weekdays and time of day commands that run continuously are active
+func getCleanupWindow() (*timewindow.TimeWindow, error) {
+	cleanupDays, cleanupDaysErr := rootCmd.Flags().GetStringSlice(settings.CleanUpDaysOption)
+	cleanupStart, cleanupStartErr := rootCmd.Flags().GetString(settings.CleanUpStartOption)
+	cleanupEnd, cleanupEndErr := rootCmd.Flags().GetString(settings.CleanUpEndOption)
+	if err := errors.Join(cleanupDaysErr, cleanupStartErr, cleanupEndErr); err != nil {
+		return nil, err
 	}
-	return rdsForRr, nil
+	timezone := "Local"
+	return timewindow.New(cleanupDays, cleanupStart, cleanupEnd, timezone)
 }
 
-func getRuntimeNamespaces(ra *v1.RadixApplication) []string {
-	namespaces := make([]string, 0)
-	for _, env := range ra.Spec.Environments {
-		namespaces = append(namespaces, utils.GetEnvironmentNamespace(ra.Name, env.Name))
+// getConcurrency returns how many applications are evaluated or stopped at the same time
+func getConcurrency() (int, error) {
+	concurrency, err := rootCmd.Flags().GetInt(settings.ConcurrencyOption)
+	if err != nil {
+		return 0, err
 	}
-	return namespaces
+	if concurrency < 1 {
+		return 0, fmt.Errorf("--%s must be at least 1", settings.ConcurrencyOption)
+	}
+	return concurrency, nil
 }
 
-func getRadixApplication(ctx context.Context, kubeClient *kube.Kube, appName string) (*v1.RadixApplication, error) {
-	return kubeClient.RadixClient().RadixV1().RadixApplications(utils.GetAppNamespace(appName)).Get(ctx, appName, metav1.GetOptions{})
+// getCleaner returns a Cleaner configured by the options. Without warnings, the warning periods are left out, for
+// commands which only list RadixRegistrations.
+// It is called at the start of every run, so changes to the whitelist and policy apply without a restart.
+func getCleaner(ctx context.Context, kubeClient *kube.Kube, warnings bool) (*cleanup.Cleaner, error) {
+	options, err := getCleanupOptions(ctx, kubeClient, warnings)
+	if err != nil {
+		return nil, err
+	}
+	return cleanup.New(kubeClient.KubeClient(), kubeClient.RadixClient(), options)
 }
 
-func isWhitelisted(rr *v1.RadixRegistration) bool {
-	whitelist := getWhitelist()
-	for _, item := range whitelist {
-		if rr.Name == item {
-			return true
+// getCleanupOptions returns the options of the Cleaner returned by getCleaner
+func getCleanupOptions(ctx context.Context, kubeClient *kube.Kube, warnings bool) (cleanup.Options, error) {
+	options := cleanup.Options{DryRun: isDryRun()}
+	var err error
+	if options.Whitelist, err = getWhitelist(ctx, kubeClient); err != nil {
+		return options, err
+	}
+	if options.Policy, err = getPolicy(ctx, kubeClient); err != nil {
+		return options, err
+	}
+	if options.Concurrency, err = getConcurrency(); err != nil {
+		return options, err
+	}
+	var stopWarningPeriod, deletionWarningPeriod time.Duration
+	if warnings {
+		var stopNotifier, deletionNotifier notifier.Notifier
+		if stopWarningPeriod, stopNotifier, err = getWarningPeriod(settings.WarnDaysBeforeStopOption); err != nil {
+			return options, err
+		}
+		if deletionWarningPeriod, deletionNotifier, err = getWarningPeriod(settings.WarnDaysBeforeDeletionOption); err != nil {
+			return options, err
 		}
+		options.Notifier = cmp.Or(stopNotifier, deletionNotifier)
 	}
-	return false
-}
-
-func rrIsInactive(ctx context.Context, rrCreationTimestamp metav1.Time, rds []v1.RadixDeployment, rjs []v1.RadixJob, inactivityLimit time.Duration, action string) (bool, error) {
-	logger := log.Ctx(ctx)
-	if rrCreationTimestamp.Add(inactivityLimit).After(time.Now()) {
-		logger.Debug().Msgf("RadixRegistration is newer than inactivity limit, assuming active")
-		return false, nil
+	if options.StopThreshold, err = getInactivityThreshold(inactivity.ActionStop, stopWarningPeriod); err != nil {
+		return options, err
 	}
-
-	if len(rds) == 0 {
-		logger.Debug().Msgf("no RadixDeployments found, assuming RadixRegistration is inactive")
-		return true, nil
+	if options.DeletionThreshold, err = getInactivityThreshold(inactivity.ActionDeletion, deletionWarningPeriod); err != nil {
+		return options, err
 	}
-
-	latestRadixDeployment := SortDeploymentsByActiveFromTimestampAsc(rds)[len(rds)-1]
-	latestRadixDeploymentTimestamp := latestRadixDeployment.Status.ActiveFrom
-	logger.Debug().Msgf("most recent radixDeployment is %s, active from %s, %d hours ago", latestRadixDeployment.Name, latestRadixDeploymentTimestamp.Format(time.RFC822), int(time.Since(latestRadixDeploymentTimestamp.Time).Hours()))
-
-	latestRadixJobTimestamp := metav1.Time{Time: time.Unix(0, 0)}
-	latestRadixJob := getLatestRadixJob(rjs)
-	if latestRadixJob != nil {
-		latestRadixJobTimestamp = *latestRadixJob.Status.Created
-		logger.Debug().Msgf("most recent radixJob was %s, created %s, %d hours ago", latestRadixJob.Name, latestRadixJobTimestamp.Format(time.RFC822), int(time.Since(latestRadixJobTimestamp.Time).Hours()))
+	if options.DeletionGracePeriod, err = rootCmd.Flags().GetDuration(settings.DeletionGracePeriodOption); err != nil {
+		return options, err
 	}
-
-	latestUserMutationTimestamp, err := getLastUserMutationTimestamp(latestRadixDeployment)
-	if err != nil {
-		return false, err
+	if options.CircuitBreaker, err = getCircuitBreaker(kubeClient); err != nil {
+		return options, err
 	}
-
-	logger.Debug().Msgf("most recent manual user activity was %s, %d hours ago", latestUserMutationTimestamp.Format(time.RFC822), int(time.Since(latestUserMutationTimestamp.Time).Hours()))
-	logger.Debug().Msgf("most recent creation of RR was %s, %d hours ago", rrCreationTimestamp, int(time.Since(rrCreationTimestamp.Time).Hours()))
-	lastActivity := getMostRecentTimestamp(&latestRadixJobTimestamp, latestUserMutationTimestamp, &latestRadixDeploymentTimestamp, &rrCreationTimestamp)
-	logger.Debug().Msgf("lastActivity was %s, %d hours ago", lastActivity, int(time.Since(lastActivity.Time).Hours()))
-	if tooLongInactivity(lastActivity, inactivityLimit) {
-		logger.Debug().Msgf("last activity was %d hours ago, which is more than %d hours ago, marking for %s", int(time.Since(lastActivity.Time).Hours()), int(inactivityLimit.Hours()), action)
-		return true, nil
+	if options.BackupSink, err = getBackupSink(); err != nil {
+		return options, err
 	}
-	return false, nil
-}
-
-func getLatestRadixJob(rjs []v1.RadixJob) *v1.RadixJob {
-	if len(rjs) > 0 {
-		return &SortJobsByTimestampAsc(rjs)[len(rjs)-1]
+	if options.BackupSink != nil {
+		options.MetadataClient = getMetadataClient()
 	}
-	return nil
-}
-
-func getLastUserMutationTimestamp(radixDeployment v1.RadixDeployment) (*metav1.Time, error) {
-	latestUserMutationTimestamp := metav1.Time{Time: time.Unix(0, 0)}
-	latestUserMutation, ok := radixDeployment.Annotations["radix.equinor.com/last-user-mutation"]
-	if ok {
-		timestamp, err := time.Parse(time.RFC3339, latestUserMutation)
-		if err != nil {
-			return nil, err
-		}
-		latestUserMutationTimestamp = metav1.Time{
-			Time: timestamp,
-		}
+	if options.NoBackup, err = rootCmd.Flags().GetBool(settings.NoBackupOption); err != nil {
+		return options, err
 	}
-	return &latestUserMutationTimestamp, nil
-}
-
-func getMostRecentTimestamp(timestamps ...*metav1.Time) *metav1.Time {
-	highestTimestamp := &metav1.Time{Time: time.Unix(0, 0)}
-	for _, timestamp := range timestamps {
-		if timestamp.After(highestTimestamp.Time) {
-			highestTimestamp = timestamp
-		}
+	if options.TombstoneStore, err = getTombstoneStore(kubeClient); err != nil {
+		return options, err
 	}
-	return highestTimestamp
-}
-
-func tooLongInactivity(lastActivity *metav1.Time, ageLimit time.Duration) bool {
-	return lastActivity.Unix() < time.Now().Add(-ageLimit).Unix()
-}
-
-func SortJobsByTimestampAsc(rjs []v1.RadixJob) []v1.RadixJob {
-	sort.Slice(rjs, func(i, j int) bool {
-		return isRJ1CreatedAfterRJ2(&rjs[i], &rjs[j])
-	})
-	return rjs
-}
-
-func isRJ1CreatedAfterRJ2(rj1 *v1.RadixJob, rj2 *v1.RadixJob) bool {
-	rj1Created := rj1.CreationTimestamp
-	rj2Created := rj2.CreationTimestamp
-	return rj1Created.Before(&rj2Created)
-}
-
-func SortDeploymentsByActiveFromTimestampAsc(rds []v1.RadixDeployment) []v1.RadixDeployment {
-	target := make([]v1.RadixDeployment, len(rds))
-	copy(target, rds)
-	sort.Slice(target, func(i, j int) bool {
-		return isRD1ActiveAfterRD2(&target[j], &target[i])
-	})
-	return target
-}
-
-func isRD1ActiveAfterRD2(rd1 *v1.RadixDeployment, rd2 *v1.RadixDeployment) bool {
-	rj1ActiveFrom := rd1.Status.ActiveFrom
-	rj2ActiveFrom := rd2.Status.ActiveFrom
-	return rj2ActiveFrom.Before(&rj1ActiveFrom)
+	return options, nil
 }
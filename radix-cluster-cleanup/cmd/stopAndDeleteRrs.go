@@ -16,7 +16,9 @@ package cmd
 
 import (
 	"context"
+	"errors"
 
+	"github.com/equinor/radix-cluster-cleanup/pkg/inactivity"
 	"github.com/spf13/cobra"
 )
 
@@ -30,11 +32,15 @@ var StopAndDeleteRrsContinuouslyCommand = &cobra.Command{
 }
 
 func stopAndDeleteInactiveRrs(ctx context.Context) error {
-	err := stopRrs(ctx)
-	if err != nil {
-		return err
-	}
-	return deleteRrs(ctx)
+	return stopAndDeleteInactiveRrsFrom(ctx, nil)
+}
+
+// stopAndDeleteInactiveRrsFrom runs like stopAndDeleteInactiveRrs, on the resources returned by listResources, or
+// listed from the cluster if nil
+func stopAndDeleteInactiveRrsFrom(ctx context.Context, listResources inactivity.ListFunc) error {
+	stopErr := stopRrsFrom(ctx, listResources)
+	deleteErr := deleteRrsFrom(ctx, listResources)
+	return errors.Join(stopErr, deleteErr)
 }
 
 func init() {
@@ -16,23 +16,17 @@ package cmd
 
 import (
 	"context"
-	"strings"
-	"time"
 
-	"github.com/equinor/radix-cluster-cleanup/pkg/settings"
-	"github.com/equinor/radix-common/utils/pointers"
-	"github.com/equinor/radix-common/utils/slice"
-	"github.com/equinor/radix-operator/pkg/apis/kube"
-	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	"github.com/equinor/radix-cluster-cleanup/pkg/cleanup"
+	"github.com/equinor/radix-cluster-cleanup/pkg/inactivity"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 var stopRrsContinuouslyCommand = &cobra.Command{
 	Use:   "stop-inactive-rrs-continuously",
-	Short: "Continuously stop all components in inactive RadixRegistrations",
-	Long:  "Continuously stop all components in inactive RadixRegistrations",
+	Short: "Continuously stop all components in inactive environments of RadixRegistrations",
+	Long:  "Continuously stop all components in inactive environments of RadixRegistrations",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return runFunctionPeriodically(cmd.Context(), stopRrs)
 	},
@@ -40,77 +34,40 @@ var stopRrsContinuouslyCommand = &cobra.Command{
 
 var stopRrsCommand = &cobra.Command{
 	Use:   "stop-inactive-rrs",
-	Short: "Stop all components in inactive RadixRegistrations",
-	Long:  "Stop all components in inactive RadixRegistrations",
+	Short: "Stop all components in inactive environments of RadixRegistrations",
+	Long:  "Stop all components in inactive environments of RadixRegistrations",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return stopRrs(cmd.Context())
 	},
 }
 
 func stopRrs(ctx context.Context) error {
+	return stopRrsFrom(ctx, nil)
+}
+
+// stopRrsFrom runs like stopRrs, on the resources returned by listResources, or listed from the cluster if nil
+func stopRrsFrom(ctx context.Context, listResources inactivity.ListFunc) error {
 	kubeClient, err := getKubeUtil()
 	if err != nil {
 		return err
 	}
-	action := "stop"
-	inactiveDaysBeforeStop, err := rootCmd.Flags().GetInt64(settings.InactiveDaysBeforeStopOption)
-	if err != nil {
-		return err
-	}
-	inactivityBeforeStop := time.Hour * 24 * time.Duration(inactiveDaysBeforeStop)
-	tooInactiveRrs, err := getTooInactiveRrs(ctx, kubeClient, inactivityBeforeStop, action)
+	options, err := getCleanupOptions(ctx, kubeClient, true)
 	if err != nil {
 		return err
 	}
-
-	for _, rr := range tooInactiveRrs {
-		ctx := log.Ctx(ctx).With().Str("appName", rr.Name).Logger().WithContext(ctx)
-		err := stopRr(ctx, kubeClient, rr)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-func stopRr(ctx context.Context, kubeClient *kube.Kube, rr v1.RadixRegistration) error {
-	ra, err := getRadixApplication(ctx, kubeClient, rr.Name)
+	options.ListResources = listResources
+	cleaner, err := cleanup.New(kubeClient.KubeClient(), kubeClient.RadixClient(), options)
 	if err != nil {
 		return err
 	}
-	namespaces := getRuntimeNamespaces(ra)
-	rdsForRr, err := getRadixDeploymentsInNamespaces(ctx, kubeClient, namespaces)
+	result, err := cleaner.Stop(ctx)
 	if err != nil {
 		return err
 	}
-
-	for _, rd := range slice.FindAll(rdsForRr, rdIsActive) {
-		ctx := log.Ctx(ctx).With().Str("deployment", rd.Name).Logger().WithContext(ctx)
-		if err := scaleRdComponentsToZeroReplicas(ctx, kubeClient, rd); err != nil {
-			return err
-		}
+	if result.Interrupted {
+		log.Ctx(ctx).Info().Msgf("stop interrupted by shutdown, stopped %d of %d environments due for stop", len(result.Stopped), len(result.Due))
 	}
-
-	return nil
-}
-
-func scaleRdComponentsToZeroReplicas(ctx context.Context, kubeClient *kube.Kube, rd v1.RadixDeployment) error {
-	logger := log.Ctx(ctx)
-	componentNames := make([]string, 0)
-	for i := range rd.Spec.Components {
-		rd.Spec.Components[i].ReplicasOverride = pointers.Ptr(0)
-		componentNames = append(componentNames, rd.Spec.Components[i].Name)
-	}
-	_, err := kubeClient.RadixClient().RadixV1().RadixDeployments(rd.Namespace).Update(ctx, &rd, metav1.UpdateOptions{})
-	if err != nil {
-		return err
-	}
-	logger.Info().Msgf("scaled components %s to 0 replicas", strings.Join(componentNames, ", "))
-	return nil
-}
-
-func rdIsActive(rd v1.RadixDeployment) bool {
-	return rd.Status.Condition == v1.DeploymentActive
+	return cleanup.Summarize(ctx, inactivity.ActionStop, result.Failures)
 }
 
 func init() {
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/inactivity"
+	"github.com/equinor/radix-cluster-cleanup/pkg/settings"
+)
+
+// getInactivityThreshold returns the inactivity threshold for the stop or deletion action from the options
+func getInactivityThreshold(action string, warningPeriod time.Duration) (inactivity.Threshold, error) {
+	daysOption, maxDaysOption := settings.InactiveDaysBeforeStopOption, settings.MaxInactiveDaysBeforeStopOption
+	if action == inactivity.ActionDeletion {
+		daysOption, maxDaysOption = settings.InactiveDaysBeforeDeletionOption, settings.MaxInactiveDaysBeforeDeletionOption
+	}
+	days, err := rootCmd.Flags().GetInt64(daysOption)
+	if err != nil {
+		return inactivity.Threshold{}, err
+	}
+	maxDays, err := rootCmd.Flags().GetInt64(maxDaysOption)
+	if err != nil {
+		return inactivity.Threshold{}, err
+	}
+	if maxDays < 0 {
+		return inactivity.Threshold{}, fmt.Errorf("--%s must not be negative", maxDaysOption)
+	}
+	return inactivity.NewThreshold(action, time.Hour*24*time.Duration(days), time.Hour*24*time.Duration(maxDays), warningPeriod), nil
+}
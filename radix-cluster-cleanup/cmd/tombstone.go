@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/settings"
+	"github.com/equinor/radix-cluster-cleanup/pkg/tombstone"
+	"github.com/equinor/radix-operator/pkg/apis/kube"
+)
+
+// getTombstoneStore returns the tombstone store configured by the tombstone options, or nil if none is configured
+func getTombstoneStore(kubeClient *kube.Kube) (tombstone.Store, error) {
+	dir, dirErr := rootCmd.Flags().GetString(settings.TombstoneDirOption)
+	configMap, configMapErr := rootCmd.Flags().GetString(settings.TombstoneConfigMapOption)
+	if err := errors.Join(dirErr, configMapErr); err != nil {
+		return nil, err
+	}
+	switch {
+	case dir != "" && configMap != "":
+		return nil, fmt.Errorf("only one of --%s and --%s can be set", settings.TombstoneDirOption, settings.TombstoneConfigMapOption)
+	case dir != "":
+		return tombstone.NewDirStore(dir), nil
+	case configMap != "":
+		namespace, name, ok := strings.Cut(configMap, "/")
+		if !ok {
+			return nil, fmt.Errorf("--%s must be on the form namespace/name", settings.TombstoneConfigMapOption)
+		}
+		return tombstone.NewConfigMapStore(kubeClient.KubeClient(), namespace, name), nil
+	}
+	return nil, nil
+}
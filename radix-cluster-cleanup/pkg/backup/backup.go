@@ -0,0 +1,61 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// keyTimeFormat is used for the timestamp in backup keys, without characters needing escaping in object keys
+const keyTimeFormat = "20060102T150405Z"
+
+// Backup is an export of the resources of an application, taken before its RadixRegistration is deleted
+type Backup struct {
+	AppName           string                `json:"appName"`
+	CreatedAt         time.Time             `json:"createdAt"`
+	RadixRegistration *v1.RadixRegistration `json:"radixRegistration"`
+	RadixApplication  *v1.RadixApplication  `json:"radixApplication,omitempty"`
+	RadixDeployments  []v1.RadixDeployment  `json:"radixDeployments,omitempty"`
+	Secrets           []SecretMetadata      `json:"secrets,omitempty"`
+}
+
+// SecretMetadata names a secret. Only the metadata of secrets is read, so their type, keys and values are not included.
+type SecretMetadata struct {
+	Namespace string            `json:"namespace"`
+	Name      string            `json:"name"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+// Sink stores backups
+type Sink interface {
+	// Put stores data under a key, replacing any existing data
+	Put(ctx context.Context, key string, data []byte) error
+	// Location returns a description of where a key is stored, for logging
+	Location(key string) string
+}
+
+// Key returns the key a backup is stored under
+func (b Backup) Key() string {
+	return fmt.Sprintf("%s/%s.json", b.AppName, b.CreatedAt.UTC().Format(keyTimeFormat))
+}
+
+// Write serializes the backup and stores it in the sink, returning where it was stored
+func Write(ctx context.Context, sink Sink, b Backup) (string, error) {
+	if errs := validation.IsDNS1123Label(b.AppName); len(errs) > 0 {
+		return "", fmt.Errorf("invalid application name %q: %s", b.AppName, strings.Join(errs, ", "))
+	}
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	key := b.Key()
+	if err := sink.Put(ctx, key, data); err != nil {
+		return "", fmt.Errorf("failed to write backup of %s to %s: %w", b.AppName, sink.Location(key), err)
+	}
+	return sink.Location(key), nil
+}
@@ -0,0 +1,53 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+type dirSink struct {
+	dir string
+}
+
+// NewDirSink returns a Sink writing backups as files below a directory, e.g. a mounted persistent volume
+func NewDirSink(dir string) Sink {
+	return &dirSink{dir: dir}
+}
+
+func (s *dirSink) Put(_ context.Context, key string, data []byte) error {
+	if !filepath.IsLocal(filepath.FromSlash(key)) {
+		return fmt.Errorf("backup key %q is not within the backup directory", key)
+	}
+	path := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	// Write to a temporary file first, so an interrupted write never leaves a partial backup
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".backup-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *dirSink) Location(key string) string {
+	return s.path(key)
+}
+
+func (s *dirSink) path(key string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(key))
+}
@@ -0,0 +1,169 @@
+package backup
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+type s3Sink struct {
+	endpoint        *url.URL
+	bucket          string
+	region          string
+	accessKeyId     string
+	secretAccessKey string
+	client          *http.Client
+}
+
+// NewS3Sink returns a Sink writing backups as objects to a bucket in an S3-compatible store, e.g. MinIO.
+// Objects are addressed path-style, and requests are signed with AWS Signature Version 4.
+func NewS3Sink(endpoint, bucket, region, accessKeyId, secretAccessKey string) (Sink, error) {
+	endpointUrl, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 endpoint %q: %w", endpoint, err)
+	}
+	if endpointUrl.Scheme == "" || endpointUrl.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q: must be an absolute URL", endpoint)
+	}
+	return &s3Sink{
+		endpoint:        endpointUrl,
+		bucket:          bucket,
+		region:          region,
+		accessKeyId:     accessKeyId,
+		secretAccessKey: secretAccessKey,
+		client:          &http.Client{Timeout: 60 * time.Second},
+	}, nil
+}
+
+func (s *s3Sink) Put(ctx context.Context, key string, data []byte) error {
+	objectUrl := *s.endpoint
+	objectUrl.Path = s.objectPath(key)
+	objectUrl.RawPath = uriEncodePath(objectUrl.Path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, objectUrl.String(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	s.sign(req, data, time.Now().UTC())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("S3 responded with status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (s *s3Sink) Location(key string) string {
+	return fmt.Sprintf("s3://%s/%s", s.bucket, key)
+}
+
+func (s *s3Sink) objectPath(key string) string {
+	return "/" + s.bucket + "/" + strings.TrimPrefix(key, "/")
+}
+
+// sign adds the AWS Signature Version 4 headers to a request, signing the host and every header set on the request
+func (s *s3Sink) sign(req *http.Request, payload []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(payload)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders, canonicalHeaders := canonicalHeaders(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := strings.Join([]string{date, s.region, "s3", "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	signingKey := hmacSha256([]byte("AWS4"+s.secretAccessKey), date)
+	for _, part := range []string{s.region, "s3", "aws4_request"} {
+		signingKey = hmacSha256(signingKey, part)
+	}
+	signature := hex.EncodeToString(hmacSha256(signingKey, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s,SignedHeaders=%s,Signature=%s", s.accessKeyId, scope, signedHeaders, signature))
+}
+
+// canonicalHeaders returns the signed header names, and the canonical headers of a request, with the names in
+// lower case and sorted, one per line
+func canonicalHeaders(req *http.Request) (string, string) {
+	headers := map[string]string{"host": cmp.Or(req.Host, req.URL.Host)}
+	for name, values := range req.Header {
+		trimmed := make([]string, 0, len(values))
+		for _, value := range values {
+			trimmed = append(trimmed, strings.Join(strings.Fields(value), " "))
+		}
+		headers[strings.ToLower(name)] = strings.Join(trimmed, ",")
+	}
+	names := slices.Sorted(maps.Keys(headers))
+	var canonical strings.Builder
+	for _, name := range names {
+		canonical.WriteString(name + ":" + headers[name] + "\n")
+	}
+	return strings.Join(names, ";"), canonical.String()
+}
+
+// canonicalQuery returns the query parameters encoded and sorted by name and value
+func canonicalQuery(query url.Values) string {
+	var parameters []string
+	for name, values := range query {
+		for _, value := range values {
+			parameters = append(parameters, uriEncode(name)+"="+uriEncode(value))
+		}
+	}
+	slices.Sort(parameters)
+	return strings.Join(parameters, "&")
+}
+
+func sha256Hex(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// uriEncodePath encodes each segment of a path as required by Signature Version 4, keeping the slashes
+func uriEncodePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = uriEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// uriEncode encodes every byte except the unreserved characters, as required by Signature Version 4
+func uriEncode(value string) string {
+	var b strings.Builder
+	for _, c := range []byte(value) {
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		_, _ = fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
@@ -0,0 +1,183 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/inactivity"
+)
+
+// window is the rolling period the daily limits apply to
+const window = 24 * time.Hour
+
+// ErrTripped is returned, wrapped in a TrippedError, while the circuit breaker refuses further actions
+var ErrTripped = errors.New("circuit breaker is tripped")
+
+// Limits caps how many stops or deletions are allowed. Zero disables a limit.
+// Percentages are relative to the total given to Allow, e.g. the number of RadixRegistrations in the cluster.
+type Limits struct {
+	MaxPerRun        int
+	MaxPercentPerRun float64
+	MaxPerDay        int
+	MaxPercentPerDay float64
+}
+
+// Trip records why and when the circuit breaker tripped
+type Trip struct {
+	Action string    `json:"action"`
+	Reason string    `json:"reason"`
+	At     time.Time `json:"at"`
+}
+
+// Record is a number of stops or deletions allowed in a run
+type Record struct {
+	Action string    `json:"action"`
+	Count  int       `json:"count"`
+	At     time.Time `json:"at"`
+}
+
+// State is what the circuit breaker keeps between runs
+type State struct {
+	Tripped *Trip    `json:"tripped,omitempty"`
+	History []Record `json:"history,omitempty"`
+	// AllowNext is an action whose next run is allowed regardless of the limits, set when acknowledging a trip
+	AllowNext string `json:"allowNext,omitempty"`
+}
+
+// Store loads and saves the circuit breaker state
+type Store interface {
+	Load(ctx context.Context) (State, error)
+	Save(ctx context.Context, state State) error
+}
+
+// TrippedError is returned when an action is refused because the circuit breaker is, or just got, tripped
+type TrippedError struct {
+	Trip Trip
+}
+
+func (e *TrippedError) Error() string {
+	return fmt.Sprintf("%s: %s at %s: %s", ErrTripped, e.Trip.Action, e.Trip.At.UTC().Format(time.RFC3339), e.Trip.Reason)
+}
+
+func (e *TrippedError) Unwrap() error {
+	return ErrTripped
+}
+
+// Breaker refuses stops and deletions exceeding the limits for their action, and every action after that, until
+// it is acknowledged
+type Breaker struct {
+	store  Store
+	limits map[string]Limits
+	clock  inactivity.Clock
+}
+
+// New returns a Breaker with limits per action, keeping its state in the store, and telling the time of actions with
+// the clock
+func New(store Store, limits map[string]Limits, clock inactivity.Clock) *Breaker {
+	return &Breaker{store: store, limits: limits, clock: clock}
+}
+
+// Allow checks if count more of an action are within its limits, out of a total the percentage limits are relative
+// to, and records them if they are. If not, the circuit breaker trips and a TrippedError is returned.
+// With dryRun, nothing is recorded, and a breach is reported without tripping.
+func (b *Breaker) Allow(ctx context.Context, action string, count, total int, dryRun bool) error {
+	state, err := b.store.Load(ctx)
+	if err != nil {
+		return err
+	}
+	if state.Tripped != nil {
+		return &TrippedError{Trip: *state.Tripped}
+	}
+	now := b.clock.Now().UTC()
+	state.History = pruneHistory(state.History, now)
+	if count == 0 {
+		return nil
+	}
+	if state.AllowNext == action {
+		if dryRun {
+			return nil
+		}
+		state.AllowNext = ""
+	} else if reason, exceeded := b.exceeded(action, count, total, state.History, now); exceeded {
+		trip := Trip{Action: action, Reason: reason, At: now}
+		if dryRun {
+			return &TrippedError{Trip: trip}
+		}
+		state.Tripped = &trip
+		if err := b.store.Save(ctx, state); err != nil {
+			return errors.Join(&TrippedError{Trip: trip}, err)
+		}
+		return &TrippedError{Trip: trip}
+	}
+	if dryRun {
+		return nil
+	}
+	state.History = append(state.History, Record{Action: action, Count: count, At: now})
+	return b.store.Save(ctx, state)
+}
+
+// Acknowledge resets a tripped circuit breaker, and returns the trip it was reset from, if any.
+// With allowNext, the next run of the action it tripped on is allowed regardless of the limits.
+func (b *Breaker) Acknowledge(ctx context.Context, allowNext bool) (*Trip, error) {
+	state, err := b.store.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	trip := state.Tripped
+	if trip == nil {
+		return nil, nil
+	}
+	state.Tripped = nil
+	if allowNext {
+		state.AllowNext = trip.Action
+	}
+	state.History = pruneHistory(state.History, b.clock.Now().UTC())
+	if err := b.store.Save(ctx, state); err != nil {
+		return nil, err
+	}
+	return trip, nil
+}
+
+func (b *Breaker) exceeded(action string, count, total int, history []Record, now time.Time) (string, bool) {
+	limits, ok := b.limits[action]
+	if !ok {
+		return "", false
+	}
+	countLastDay := count
+	for _, record := range history {
+		if record.Action == action && now.Sub(record.At) < window {
+			countLastDay += record.Count
+		}
+	}
+	switch {
+	case limits.MaxPerRun > 0 && count > limits.MaxPerRun:
+		return fmt.Sprintf("%d exceeds the limit of %d per run", count, limits.MaxPerRun), true
+	case limits.MaxPercentPerRun > 0 && percent(count, total) > limits.MaxPercentPerRun:
+		return fmt.Sprintf("%d of %d (%.1f%%) exceeds the limit of %.1f%% per run", count, total, percent(count, total), limits.MaxPercentPerRun), true
+	case limits.MaxPerDay > 0 && countLastDay > limits.MaxPerDay:
+		return fmt.Sprintf("%d within 24 hours exceeds the limit of %d per day", countLastDay, limits.MaxPerDay), true
+	case limits.MaxPercentPerDay > 0 && percent(countLastDay, total) > limits.MaxPercentPerDay:
+		return fmt.Sprintf("%d of %d (%.1f%%) within 24 hours exceeds the limit of %.1f%% per day", countLastDay, total, percent(countLastDay, total), limits.MaxPercentPerDay), true
+	}
+	return "", false
+}
+
+func percent(count, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return 100 * float64(count) / float64(total)
+}
+
+// pruneHistory removes records older than the rolling window
+func pruneHistory(history []Record, now time.Time) []Record {
+	var recent []Record
+	for _, record := range history {
+		if now.Sub(record.At) < window {
+			recent = append(recent, record)
+		}
+	}
+	return recent
+}
@@ -0,0 +1,161 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/circuitbreaker"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+const action = "deletion"
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func newBreaker(limits circuitbreaker.Limits) (*circuitbreaker.Breaker, *circuitbreaker.MemoryStore, *testClock) {
+	store := circuitbreaker.NewMemoryStore()
+	clock := &testClock{now: time.Date(2026, time.June, 1, 0, 0, 0, 0, time.UTC)}
+	return circuitbreaker.New(store, map[string]circuitbreaker.Limits{action: limits}, clock), store, clock
+}
+
+func assertAllowed(t *testing.T, breaker *circuitbreaker.Breaker, count, total int) {
+	t.Helper()
+	if err := breaker.Allow(context.Background(), action, count, total, false); err != nil {
+		t.Fatalf("expected %d of %d to be allowed, got %v", count, total, err)
+	}
+}
+
+func assertTripped(t *testing.T, breaker *circuitbreaker.Breaker, count, total int) {
+	t.Helper()
+	if err := breaker.Allow(context.Background(), action, count, total, false); !errors.Is(err, circuitbreaker.ErrTripped) {
+		t.Fatalf("expected %d of %d to trip the circuit breaker, got %v", count, total, err)
+	}
+}
+
+func TestAllowPerRunLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  circuitbreaker.Limits
+		allowed int
+		tripped int
+	}{
+		{name: "count", limits: circuitbreaker.Limits{MaxPerRun: 2}, allowed: 2, tripped: 3},
+		{name: "percent", limits: circuitbreaker.Limits{MaxPercentPerRun: 20}, allowed: 2, tripped: 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			breaker, _, _ := newBreaker(test.limits)
+			assertAllowed(t, breaker, test.allowed, 10)
+			assertTripped(t, breaker, test.tripped, 10)
+			// A tripped circuit breaker refuses every run until acknowledged
+			assertTripped(t, breaker, 1, 10)
+		})
+	}
+}
+
+func TestAllowPerDayLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits circuitbreaker.Limits
+	}{
+		{name: "count", limits: circuitbreaker.Limits{MaxPerDay: 5}},
+		{name: "percent", limits: circuitbreaker.Limits{MaxPercentPerDay: 50}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			breaker, _, clock := newBreaker(test.limits)
+			assertAllowed(t, breaker, 3, 10)
+			clock.now = clock.now.Add(time.Hour)
+			assertAllowed(t, breaker, 2, 10)
+			clock.now = clock.now.Add(time.Hour)
+			assertTripped(t, breaker, 1, 10)
+		})
+	}
+}
+
+func TestAllowPrunesDayWindow(t *testing.T) {
+	ctx := context.Background()
+	breaker, store, clock := newBreaker(circuitbreaker.Limits{MaxPerDay: 5})
+	assertAllowed(t, breaker, 3, 10)
+	clock.now = clock.now.Add(23 * time.Hour)
+	assertAllowed(t, breaker, 2, 10)
+	clock.now = clock.now.Add(2 * time.Hour)
+	// The first run is out of the window, leaving 2 within the last 24 hours
+	assertAllowed(t, breaker, 3, 10)
+
+	state, err := store.Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.History) != 2 || state.History[0].Count != 2 || state.History[1].Count != 3 {
+		t.Errorf("expected the runs within the last 24 hours to be kept, got %v", state.History)
+	}
+}
+
+func TestAllowDryRunRecordsNothing(t *testing.T) {
+	ctx := context.Background()
+	breaker, store, _ := newBreaker(circuitbreaker.Limits{MaxPerRun: 2})
+	if err := breaker.Allow(ctx, action, 3, 10, true); !errors.Is(err, circuitbreaker.ErrTripped) {
+		t.Errorf("expected a dry-run to report the breach, got %v", err)
+	}
+	if err := breaker.Allow(ctx, action, 2, 10, true); err != nil {
+		t.Errorf("expected a dry-run within the limits to be allowed, got %v", err)
+	}
+	state, err := store.Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Tripped != nil || len(state.History) > 0 {
+		t.Errorf("expected no state from dry-runs, got %+v", state)
+	}
+}
+
+func TestAcknowledge(t *testing.T) {
+	stores := map[string]func() circuitbreaker.Store{
+		"memory": func() circuitbreaker.Store {
+			return circuitbreaker.NewMemoryStore()
+		},
+		"configmap": func() circuitbreaker.Store {
+			return circuitbreaker.NewConfigMapStore(kubefake.NewSimpleClientset(), "radix-cluster-cleanup", "circuit-breaker")
+		},
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			clock := &testClock{now: time.Date(2026, time.June, 1, 0, 0, 0, 0, time.UTC)}
+			store := newStore()
+			breaker := circuitbreaker.New(store, map[string]circuitbreaker.Limits{action: {MaxPerRun: 2}}, clock)
+
+			if trip, err := breaker.Acknowledge(ctx, false); err != nil || trip != nil {
+				t.Fatalf("expected nothing to acknowledge, got %v, %v", trip, err)
+			}
+			assertTripped(t, breaker, 3, 10)
+			// Another Breaker on the same store, like acknowledge-circuit-breaker in another process, sees the trip
+			acknowledger := circuitbreaker.New(store, nil, clock)
+			trip, err := acknowledger.Acknowledge(ctx, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if trip == nil || trip.Action != action {
+				t.Fatalf("expected the trip on %s to be acknowledged, got %v", action, trip)
+			}
+			assertAllowed(t, breaker, 2, 10)
+			assertTripped(t, breaker, 3, 10)
+
+			if _, err := acknowledger.Acknowledge(ctx, true); err != nil {
+				t.Fatal(err)
+			}
+			// Only the next run is allowed regardless of the limits
+			assertAllowed(t, breaker, 3, 10)
+			assertTripped(t, breaker, 3, 10)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/backup"
+	"github.com/equinor/radix-cluster-cleanup/pkg/inactivity"
+	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	"github.com/equinor/radix-operator/pkg/apis/utils"
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var errNoBackup = errors.New("no backup sink configured, and deletion without backup not allowed")
+
+// backupRr exports the RadixRegistration, RadixApplication, latest RadixDeployment in each environment and the
+// metadata of secrets in the app and environment namespaces to the backup sink.
+// The RadixRegistration must not be deleted if an error is returned.
+func (c *Cleaner) backupRr(ctx context.Context, resources *inactivity.Resources, rr v1.RadixRegistration) error {
+	logger := log.Ctx(ctx)
+	sink := c.options.BackupSink
+	if sink == nil {
+		if !c.options.NoBackup {
+			return errNoBackup
+		}
+		logger.Warn().Msg("no backup sink configured, deleting RadixRegistration without backup")
+		return nil
+	}
+	rrBackup, err := c.collectBackup(ctx, resources, rr)
+	if err != nil {
+		return fmt.Errorf("failed to collect backup of %s: %w", rr.Name, err)
+	}
+	if c.options.DryRun {
+		logger.Info().Msgf("dry-run: would back up RadixRegistration to %s", sink.Location(rrBackup.Key()))
+		return nil
+	}
+	location, err := backup.Write(ctx, sink, rrBackup)
+	if err != nil {
+		return err
+	}
+	logger.Info().Msgf("backed up RadixRegistration to %s", location)
+	return nil
+}
+
+func (c *Cleaner) collectBackup(ctx context.Context, resources *inactivity.Resources, rr v1.RadixRegistration) (backup.Backup, error) {
+	rrBackup := backup.Backup{
+		AppName:           rr.Name,
+		CreatedAt:         c.now().UTC().Truncate(time.Second),
+		RadixRegistration: &rr,
+	}
+	namespaces := []string{utils.GetAppNamespace(rr.Name)}
+	if ra := resources.RadixApplication(rr.Name); ra != nil {
+		rrBackup.RadixApplication = ra
+		for _, env := range ra.Spec.Environments {
+			namespace := utils.GetEnvironmentNamespace(rr.Name, env.Name)
+			namespaces = append(namespaces, namespace)
+			if rds := resources.RadixDeployments(namespace); len(rds) > 0 {
+				sortedRds := inactivity.SortDeploymentsByActiveFromTimestampAsc(rds)
+				rrBackup.RadixDeployments = append(rrBackup.RadixDeployments, sortedRds[len(sortedRds)-1])
+			}
+		}
+	}
+	secretsResource := corev1.SchemeGroupVersion.WithResource("secrets")
+	for _, namespace := range namespaces {
+		secrets, err := c.options.MetadataClient.Resource(secretsResource).Namespace(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return rrBackup, err
+		}
+		for _, secret := range secrets.Items {
+			rrBackup.Secrets = append(rrBackup.Secrets, backup.SecretMetadata{
+				Namespace: secret.Namespace,
+				Name:      secret.Name,
+				Labels:    secret.Labels,
+			})
+		}
+	}
+	return rrBackup, nil
+}
@@ -0,0 +1,44 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/circuitbreaker"
+	"github.com/equinor/radix-cluster-cleanup/pkg/inactivity"
+	"github.com/equinor/radix-cluster-cleanup/pkg/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+// allowAction asks the circuit breaker, if any, if count stops or deletions, out of the total the percentage limits
+// are relative to, can proceed. The run must abort if an error is returned.
+func (c *Cleaner) allowAction(ctx context.Context, action string, count, total int) error {
+	breaker := c.options.CircuitBreaker
+	if breaker == nil {
+		return nil
+	}
+	dryRun := c.options.DryRun
+	err := breaker.Allow(ctx, action, count, total, dryRun)
+	if errors.Is(err, circuitbreaker.ErrTripped) {
+		metrics.AddError(metrics.ErrorKindCircuitBreaker)
+		metrics.SetCircuitBreakerTripped(!dryRun)
+		log.Ctx(ctx).Error().Err(err).Bool("dryRun", dryRun).Msgf("circuit breaker refused %d %s, run acknowledge-circuit-breaker after verifying the cleanup is correct", count, action)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	metrics.SetCircuitBreakerTripped(false)
+	return nil
+}
+
+// countRunningEnvironments returns the number of environments with an active RadixDeployment in the cluster
+func countRunningEnvironments(resources *inactivity.Resources) int {
+	count := 0
+	for rd := range resources.AllRadixDeployments() {
+		if rdIsActive(*rd) {
+			count++
+		}
+	}
+	return count
+}
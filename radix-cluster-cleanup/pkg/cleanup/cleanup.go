@@ -0,0 +1,256 @@
+// Package cleanup evaluates RadixRegistrations for inactivity, and stops and deletes those inactive for too long. It
+// takes its configuration from Options, not from command line flags, so other tools can embed it.
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/backup"
+	"github.com/equinor/radix-cluster-cleanup/pkg/circuitbreaker"
+	"github.com/equinor/radix-cluster-cleanup/pkg/inactivity"
+	"github.com/equinor/radix-cluster-cleanup/pkg/metrics"
+	"github.com/equinor/radix-cluster-cleanup/pkg/notifier"
+	"github.com/equinor/radix-cluster-cleanup/pkg/policy"
+	"github.com/equinor/radix-cluster-cleanup/pkg/tombstone"
+	"github.com/equinor/radix-cluster-cleanup/pkg/whitelist"
+	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	"github.com/equinor/radix-operator/pkg/apis/utils"
+	radixclient "github.com/equinor/radix-operator/pkg/client/clientset/versioned"
+	"github.com/rs/zerolog/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
+)
+
+// ActionExempt is the action of the exempt RadixRegistrations returned by Exempt
+const ActionExempt = "exempt"
+
+// ActionWarn is the action listed for RadixRegistrations whose owners are only warned, see EvaluateResult.Warn
+const ActionWarn = "warn"
+
+// Options configures a Cleaner. The zero value of each optional field disables what it configures.
+type Options struct {
+	// StopThreshold is how long environments may be inactive before they are stopped, and warned about before that
+	StopThreshold inactivity.Threshold
+	// DeletionThreshold is how long RadixRegistrations may be inactive before they are deleted, and warned about
+	// before that
+	DeletionThreshold inactivity.Threshold
+	// DeletionGracePeriod is how long a RadixRegistration must have been marked for deletion before it is deleted. A
+	// dry-run takes RadixRegistrations not marked as marked when they went past their inactivity limit.
+	DeletionGracePeriod time.Duration
+	// Whitelist exempts RadixRegistrations from cleanup
+	Whitelist *whitelist.Whitelist
+	// Policy is evaluated before the thresholds
+	Policy *policy.Policy
+	// Notifier warns owners. Required if either threshold has a warning period.
+	Notifier notifier.Notifier
+	// CircuitBreaker limits how many environments are stopped and RadixRegistrations deleted
+	CircuitBreaker *circuitbreaker.Breaker
+	// BackupSink receives a backup of each RadixRegistration before it is deleted
+	BackupSink backup.Sink
+	// MetadataClient lists the metadata of secrets for backups, so their values are never read. Required with a
+	// BackupSink.
+	MetadataClient metadata.Interface
+	// NoBackup allows Delete to delete RadixRegistrations without a backup when there is no BackupSink. Delete refuses
+	// to run without one or the other.
+	NoBackup bool
+	// TombstoneStore records each deleted RadixRegistration, so it can be re-created
+	TombstoneStore tombstone.Store
+	// Concurrency is how many applications are evaluated and stopped at the same time, at least 1
+	Concurrency int
+	// DryRun logs every change, and submits them as server-side dry-run requests without persisting them
+	DryRun bool
+	// Clock tells the time activity is evaluated at and marks are set with. Defaults to the system clock.
+	Clock inactivity.Clock
+	// ListResources returns the resources Evaluate, Stop and Delete act on, e.g. from informer caches. Defaults to
+	// listing them with the radix client.
+	ListResources inactivity.ListFunc
+}
+
+// Cleaner evaluates RadixRegistrations for inactivity, and stops and deletes those inactive for too long
+type Cleaner struct {
+	kubeClient  kubernetes.Interface
+	radixClient radixclient.Interface
+	options     Options
+	evaluator   *inactivity.Evaluator
+}
+
+// EvaluateResult is the outcome of evaluating all RadixRegistrations, or their environments, for an action
+type EvaluateResult struct {
+	Action string
+	// Due are past their inactivity limit, and not only warned about by a policy rule
+	Due []inactivity.InactiveRr
+	// Warn are within the warning period before their inactivity limit, or only warned about by a policy rule
+	Warn     []inactivity.InactiveRr
+	Failures []Failure
+}
+
+// StopResult is the outcome of a stop run
+type StopResult struct {
+	// Due are the environments past their inactivity limit
+	Due []inactivity.InactiveRr
+	// Warned are the environments whose owners were warned in this run
+	Warned []inactivity.InactiveRr
+	// Stopped are the environments stopped in this run, or which would have been in a dry run
+	Stopped []inactivity.InactiveRr
+	// Unmarked are the RadixRegistrations with new activity, which had their marks for stop removed
+	Unmarked []string
+	Failures []Failure
+	// Interrupted is set if the run was shut down before it finished, see WithGracePeriod
+	Interrupted bool
+}
+
+// DeleteResult is the outcome of a deletion run
+type DeleteResult struct {
+	// Due are the RadixRegistrations past their inactivity limit
+	Due []inactivity.InactiveRr
+	// Warned are the RadixRegistrations whose owners were warned in this run
+	Warned []inactivity.InactiveRr
+	// Pending are marked for deletion, but still within the grace period
+	Pending []PendingDeletion
+	// Deleted are the RadixRegistrations deleted in this run, or which would have been in a dry run
+	Deleted []inactivity.InactiveRr
+	// Unmarked are the RadixRegistrations with new activity, which had their marks for deletion removed
+	Unmarked []string
+	Failures []Failure
+	// Interrupted is set if the run was shut down before it finished, see WithGracePeriod
+	Interrupted bool
+}
+
+// PendingDeletion is a RadixRegistration marked for deletion, and when the grace period ends
+type PendingDeletion struct {
+	inactivity.InactiveRr
+	DeleteAfter time.Time
+}
+
+// New returns a Cleaner working on the cluster of the clients
+func New(kubeClient kubernetes.Interface, radixClient radixclient.Interface, options Options) (*Cleaner, error) {
+	if options.StopThreshold.Action != inactivity.ActionStop {
+		return nil, fmt.Errorf("stop threshold must be for action %s, not %q", inactivity.ActionStop, options.StopThreshold.Action)
+	}
+	if options.DeletionThreshold.Action != inactivity.ActionDeletion {
+		return nil, fmt.Errorf("deletion threshold must be for action %s, not %q", inactivity.ActionDeletion, options.DeletionThreshold.Action)
+	}
+	if options.Notifier == nil && (options.StopThreshold.WarningPeriod > 0 || options.DeletionThreshold.WarningPeriod > 0) {
+		return nil, fmt.Errorf("a warning period requires a notifier")
+	}
+	if options.BackupSink != nil && options.MetadataClient == nil {
+		return nil, fmt.Errorf("a backup sink requires a metadata client")
+	}
+	if options.Concurrency < 1 {
+		return nil, fmt.Errorf("concurrency must be at least 1")
+	}
+	if options.Clock == nil {
+		options.Clock = inactivity.RealClock{}
+	}
+	return &Cleaner{
+		kubeClient:  kubeClient,
+		radixClient: radixClient,
+		options:     options,
+		evaluator:   inactivity.NewEvaluator(options.Clock, options.Whitelist, options.Policy, options.Concurrency),
+	}, nil
+}
+
+// Evaluator returns the evaluator the Cleaner evaluates activity with
+func (c *Cleaner) Evaluator() *inactivity.Evaluator {
+	return c.evaluator
+}
+
+// Threshold returns the inactivity threshold for the stop or deletion action
+func (c *Cleaner) Threshold(action string) inactivity.Threshold {
+	if action == inactivity.ActionDeletion {
+		return c.options.DeletionThreshold
+	}
+	return c.options.StopThreshold
+}
+
+// Evaluate finds the environments due for stop, or RadixRegistrations due for deletion, and those to warn about,
+// without changing anything
+func (c *Cleaner) Evaluate(ctx context.Context, action string) (*EvaluateResult, error) {
+	resources, err := c.listResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+	failures := newFailures()
+	result := c.evaluate(ctx, resources, action, failures)
+	result.Failures = failures.list()
+	return result, nil
+}
+
+// Exempt returns the RadixRegistrations exempt from cleanup, with the reason they are exempt
+func (c *Cleaner) Exempt(ctx context.Context) ([]inactivity.Activity, error) {
+	rrs, err := c.listRegistrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var exemptRrs []inactivity.Activity
+	for _, rr := range rrs {
+		if exemption, ok := c.evaluator.Exemption(rr); ok {
+			exemptRrs = append(exemptRrs, inactivity.Activity{AppName: rr.Name, Action: ActionExempt, RrCreated: rr.CreationTimestamp, Exemption: exemption})
+		}
+	}
+	return exemptRrs, nil
+}
+
+func (c *Cleaner) evaluate(ctx context.Context, resources *inactivity.Resources, action string, failures *failures) *EvaluateResult {
+	inactiveRrs, evaluationFailures := c.evaluator.TooInactive(ctx, resources, c.Threshold(action), action == inactivity.ActionStop)
+	for _, failure := range evaluationFailures {
+		logContext := log.Ctx(ctx).With().Str("appName", failure.AppName)
+		if failure.Environment != "" {
+			logContext = logContext.Str("environment", failure.Environment)
+		}
+		failures.add(logContext.Logger().WithContext(ctx), failure.AppName, failure.Environment, metrics.ErrorKindEvaluate, failure.Err)
+	}
+	result := &EvaluateResult{Action: action}
+	result.Due, result.Warn = splitRrsForWarning(inactiveRrs)
+	return result
+}
+
+func (c *Cleaner) now() time.Time {
+	return c.options.Clock.Now()
+}
+
+// dryRunOption returns the DryRun value for create, update, patch and delete options
+func (c *Cleaner) dryRunOption() []string {
+	if c.options.DryRun {
+		return []string{metav1.DryRunAll}
+	}
+	return nil
+}
+
+// listResources lists the snapshot of the cluster a run evaluates, stops and deletes from
+func (c *Cleaner) listResources(ctx context.Context) (*inactivity.Resources, error) {
+	if c.options.ListResources != nil {
+		return c.options.ListResources(ctx)
+	}
+	return inactivity.ListResources(ctx, c.radixClient)
+}
+
+func (c *Cleaner) listRegistrations(ctx context.Context) ([]*v1.RadixRegistration, error) {
+	var rrs []*v1.RadixRegistration
+	err := inactivity.EachListItem(ctx, func(opts metav1.ListOptions) (runtime.Object, error) {
+		return c.radixClient.RadixV1().RadixRegistrations().List(ctx, opts)
+	}, func(rr *v1.RadixRegistration) {
+		rrs = append(rrs, rr)
+	})
+	return rrs, err
+}
+
+func (c *Cleaner) getRadixDeploymentsInNamespaces(ctx context.Context, namespaces []string) ([]v1.RadixDeployment, error) {
+	rdsForRr := make([]v1.RadixDeployment, 0)
+	for _, ns := range namespaces {
+		rds, err := c.radixClient.RadixV1().RadixDeployments(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		rdsForRr = append(rdsForRr, rds.Items...)
+	}
+	return rdsForRr, nil
+}
+
+func (c *Cleaner) getRadixApplication(ctx context.Context, appName string) (*v1.RadixApplication, error) {
+	return c.radixClient.RadixV1().RadixApplications(utils.GetAppNamespace(appName)).Get(ctx, appName, metav1.GetOptions{})
+}
@@ -0,0 +1,560 @@
+package cleanup_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/backup"
+	"github.com/equinor/radix-cluster-cleanup/pkg/cleanup"
+	"github.com/equinor/radix-cluster-cleanup/pkg/inactivity"
+	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	radixfake "github.com/equinor/radix-operator/pkg/client/clientset/versioned/fake"
+	"github.com/prometheus/client_golang/prometheus"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const day = 24 * time.Hour
+
+var now = time.Date(2026, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+// newApp returns the RadixRegistration, RadixApplication and active RadixDeployment of an application with one
+// environment and one component, last deployed at activeFrom
+func newApp(name string, activeFrom time.Time) (*v1.RadixRegistration, *v1.RadixApplication, *v1.RadixDeployment) {
+	rr := &v1.RadixRegistration{ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(now.AddDate(-1, 0, 0))}}
+	ra := &v1.RadixApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: name + "-app"},
+		Spec:       v1.RadixApplicationSpec{Environments: []v1.Environment{{Name: "prod"}}},
+	}
+	rd := &v1.RadixDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "prod-1", Namespace: name + "-prod"},
+		Spec:       v1.RadixDeploymentSpec{Components: []v1.RadixDeployComponent{{Name: "web"}}},
+		Status:     v1.RadixDeployStatus{ActiveFrom: metav1.NewTime(activeFrom), Condition: v1.DeploymentActive},
+	}
+	return rr, ra, rd
+}
+
+func newCleaner(t *testing.T) (*cleanup.Cleaner, *radixfake.Clientset) {
+	t.Helper()
+	return newCleanerWith(t, func(options *cleanup.Options) {
+		options.NoBackup = true
+	})
+}
+
+// newCleanerWith returns a Cleaner of an idle and a busy application, with the options changed by configure
+func newCleanerWith(t *testing.T, configure func(options *cleanup.Options)) (*cleanup.Cleaner, *radixfake.Clientset) {
+	t.Helper()
+	idleRr, idleRa, idleRd := newApp("idle", now.Add(-60*day))
+	busyRr, busyRa, busyRd := newApp("busy", now.Add(-day))
+	radixClient := radixfake.NewSimpleClientset(idleRr, idleRa, idleRd, busyRr, busyRa, busyRd)
+	options := cleanup.Options{
+		StopThreshold:     inactivity.NewThreshold(inactivity.ActionStop, 7*day, 0, 0),
+		DeletionThreshold: inactivity.NewThreshold(inactivity.ActionDeletion, 28*day, 0, 0),
+		Concurrency:       2,
+		Clock:             fixedClock(now),
+	}
+	configure(&options)
+	cleaner, err := cleanup.New(kubefake.NewSimpleClientset(), radixClient, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cleaner, radixClient
+}
+
+func appNames(inactiveRrs []inactivity.InactiveRr) []string {
+	var names []string
+	for _, inactiveRr := range inactiveRrs {
+		names = append(names, inactiveRr.Rr.Name)
+	}
+	return names
+}
+
+func TestNewRequiresNotifierForWarnings(t *testing.T) {
+	_, err := cleanup.New(kubefake.NewSimpleClientset(), radixfake.NewSimpleClientset(), cleanup.Options{
+		StopThreshold:     inactivity.NewThreshold(inactivity.ActionStop, 7*day, 0, 2*day),
+		DeletionThreshold: inactivity.NewThreshold(inactivity.ActionDeletion, 28*day, 0, 0),
+		Concurrency:       1,
+	})
+	if err == nil {
+		t.Error("expected an error for a warning period without a notifier")
+	}
+}
+
+func TestStopAndRestore(t *testing.T) {
+	ctx := context.Background()
+	cleaner, radixClient := newCleaner(t)
+
+	result, err := cleaner.Stop(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Failures) > 0 {
+		t.Fatalf("unexpected failures: %v", result.Failures)
+	}
+	if names := appNames(result.Stopped); len(names) != 1 || names[0] != "idle" {
+		t.Fatalf("expected idle to be stopped, got %v", names)
+	}
+	rd, err := radixClient.RadixV1().RadixDeployments("idle-prod").Get(ctx, "prod-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replicas := rd.Spec.Components[0].ReplicasOverride; replicas == nil || *replicas != 0 {
+		t.Errorf("expected replicasOverride 0, got %v", replicas)
+	}
+	rr, err := radixClient.RadixV1().RadixRegistrations().Get(ctx, "idle", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if markedAt := rr.Annotations["radix.equinor.com/cleanup-marked-for-stop-in-prod-at"]; markedAt != now.Format(time.RFC3339) {
+		t.Errorf("expected idle to be marked for stop at %s, got %q", now.Format(time.RFC3339), markedAt)
+	}
+
+	restored, err := cleaner.Restore(ctx, "idle", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restored) != 1 {
+		t.Fatalf("expected 1 RadixDeployment to be restored, got %d", len(restored))
+	}
+	rd, err = radixClient.RadixV1().RadixDeployments("idle-prod").Get(ctx, "prod-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replicas := rd.Spec.Components[0].ReplicasOverride; replicas != nil {
+		t.Errorf("expected replicasOverride to be unset, got %d", *replicas)
+	}
+	if lastUserMutation := rd.Annotations[inactivity.LastUserMutationAnnotation]; lastUserMutation != now.Format(time.RFC3339) {
+		t.Errorf("expected last user mutation %s, got %q", now.Format(time.RFC3339), lastUserMutation)
+	}
+}
+
+// counterValue returns the value of a counter registered with the default Prometheus registry
+func counterValue(t *testing.T, name string) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() == name {
+			return family.GetMetric()[0].GetCounter().GetValue()
+		}
+	}
+	t.Fatalf("counter %s not found", name)
+	return 0
+}
+
+func TestStopAgainChangesNothing(t *testing.T) {
+	ctx := context.Background()
+	cleaner, radixClient := newCleaner(t)
+	counters := []string{"radix_cluster_cleanup_components_stopped_total", "radix_cluster_cleanup_environments_stopped_total"}
+	before := make(map[string]float64)
+	for _, counter := range counters {
+		before[counter] = counterValue(t, counter)
+	}
+
+	for range 2 {
+		result, err := cleaner.Stop(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if names := appNames(result.Stopped); len(names) != 1 || names[0] != "idle" {
+			t.Fatalf("expected idle to be stopped, got %v", names)
+		}
+	}
+	for _, counter := range counters {
+		if added := counterValue(t, counter) - before[counter]; added != 1 {
+			t.Errorf("expected %s to be incremented once, got %v", counter, added)
+		}
+	}
+	rdPatches := 0
+	for _, action := range radixClient.Actions() {
+		if action.GetVerb() == "patch" && action.GetResource().Resource == "radixdeployments" {
+			rdPatches++
+		}
+	}
+	if rdPatches != 1 {
+		t.Errorf("expected the RadixDeployment to be patched once, got %d patches", rdPatches)
+	}
+}
+
+func TestStopRebuildsPatchAfterConcurrentChange(t *testing.T) {
+	tests := map[string]error{
+		"conflict":       kubeerrors.NewConflict(v1.SchemeGroupVersion.WithResource("radixdeployments").GroupResource(), "prod-1", errors.New("modified")),
+		"failed test op": kubeerrors.NewInvalid(v1.SchemeGroupVersion.WithKind("RadixDeployment").GroupKind(), "prod-1", nil),
+	}
+	for name, patchErr := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			cleaner, radixClient := newCleaner(t)
+			gvr := v1.SchemeGroupVersion.WithResource("radixdeployments")
+			patches := 0
+			radixClient.PrependReactor("patch", "radixdeployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if patches++; patches > 1 {
+					return false, nil, nil
+				}
+				// A component is added in front of web after the patch was built, so its index is no longer 0
+				obj, err := radixClient.Tracker().Get(gvr, "idle-prod", "prod-1")
+				if err != nil {
+					return true, nil, err
+				}
+				rd := obj.(*v1.RadixDeployment).DeepCopy()
+				rd.Spec.Components = append([]v1.RadixDeployComponent{{Name: "api"}}, rd.Spec.Components...)
+				if err := radixClient.Tracker().Update(gvr, rd, "idle-prod"); err != nil {
+					return true, nil, err
+				}
+				return true, nil, patchErr
+			})
+
+			result, err := cleaner.Stop(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Failures) > 0 {
+				t.Fatalf("unexpected failures: %v", result.Failures)
+			}
+			if patches != 2 {
+				t.Errorf("expected the patch to be retried once, got %d patches", patches)
+			}
+			rd, err := radixClient.RadixV1().RadixDeployments("idle-prod").Get(ctx, "prod-1", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, component := range rd.Spec.Components {
+				if replicas := component.ReplicasOverride; replicas == nil || *replicas != 0 {
+					t.Errorf("expected replicasOverride 0 of %s, got %v", component.Name, replicas)
+				}
+			}
+		})
+	}
+}
+
+func TestRestoreContinuesAfterFailure(t *testing.T) {
+	ctx := context.Background()
+	cleaner, radixClient := newCleaner(t)
+	if _, err := cleaner.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	busyRd, err := radixClient.RadixV1().RadixDeployments("busy-prod").Get(ctx, "prod-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	busyRd.Annotations = map[string]string{"radix.equinor.com/cleanup-replicas-override": "{}"}
+	if _, err := radixClient.RadixV1().RadixDeployments("busy-prod").Update(ctx, busyRd, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	radixClient.PrependReactor("patch", "radixdeployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetNamespace() == "busy-prod" {
+			return true, nil, errors.New("patch refused")
+		}
+		return false, nil, nil
+	})
+
+	restored, err := cleaner.Restore(ctx, "", time.Time{})
+	if err == nil || !strings.Contains(err.Error(), "busy-prod/prod-1") {
+		t.Errorf("expected an error naming busy-prod/prod-1, got %v", err)
+	}
+	if len(restored) != 1 || restored[0].Namespace != "idle-prod" {
+		t.Errorf("expected idle-prod/prod-1 to be restored, got %d RadixDeployments", len(restored))
+	}
+}
+
+func TestRestoreStoppedAfter(t *testing.T) {
+	ctx := context.Background()
+	cleaner, radixClient := newCleaner(t)
+	if _, err := cleaner.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// busy looks stopped, but has no record of when
+	busyRd, err := radixClient.RadixV1().RadixDeployments("busy-prod").Get(ctx, "prod-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	busyRd.Annotations = map[string]string{"radix.equinor.com/cleanup-replicas-override": "{}"}
+	if _, err := radixClient.RadixV1().RadixDeployments("busy-prod").Update(ctx, busyRd, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := cleaner.Restore(ctx, "", now.Add(day))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restored) > 0 {
+		t.Errorf("expected nothing stopped after %s to be restored, got %d RadixDeployments", now.Add(day), len(restored))
+	}
+	restored, err = cleaner.Restore(ctx, "", now.Add(-day))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restored) != 1 || restored[0].Namespace != "idle-prod" {
+		t.Errorf("expected only idle-prod/prod-1 to be restored, got %d RadixDeployments", len(restored))
+	}
+}
+
+func TestStopCollectsFailuresOfConcurrentApps(t *testing.T) {
+	ctx := context.Background()
+	var objects []runtime.Object
+	for i := range 6 {
+		rr, ra, rd := newApp(fmt.Sprintf("idle-%d", i), now.Add(-60*day))
+		objects = append(objects, rr, ra, rd)
+	}
+	radixClient := radixfake.NewSimpleClientset(objects...)
+	radixClient.PrependReactor("patch", "radixdeployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetNamespace() == "idle-1-prod" || action.GetNamespace() == "idle-4-prod" {
+			return true, nil, errors.New("patch refused")
+		}
+		return false, nil, nil
+	})
+	cleaner, err := cleanup.New(kubefake.NewSimpleClientset(), radixClient, cleanup.Options{
+		StopThreshold:     inactivity.NewThreshold(inactivity.ActionStop, 7*day, 0, 0),
+		DeletionThreshold: inactivity.NewThreshold(inactivity.ActionDeletion, 28*day, 0, 0),
+		Concurrency:       3,
+		Clock:             fixedClock(now),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := cleaner.Stop(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var failed []string
+	for _, failure := range result.Failures {
+		failed = append(failed, failure.AppName)
+	}
+	if !slices.Equal(failed, []string{"idle-1", "idle-4"}) {
+		t.Errorf("expected idle-1 and idle-4 to fail, got %v", result.Failures)
+	}
+	stopped := appNames(result.Stopped)
+	slices.Sort(stopped)
+	if !slices.Equal(stopped, []string{"idle-0", "idle-2", "idle-3", "idle-5"}) {
+		t.Errorf("expected the other applications to be stopped, got %v", stopped)
+	}
+}
+
+func TestEvaluateChangesNothing(t *testing.T) {
+	ctx := context.Background()
+	cleaner, radixClient := newCleaner(t)
+
+	result, err := cleaner.Evaluate(ctx, inactivity.ActionDeletion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := appNames(result.Due); len(names) != 1 || names[0] != "idle" {
+		t.Fatalf("expected idle to be due for deletion, got %v", names)
+	}
+	rr, err := radixClient.RadixV1().RadixRegistrations().Get(ctx, "idle", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rr.Annotations) > 0 {
+		t.Errorf("expected no annotations, got %v", rr.Annotations)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+	cleaner, radixClient := newCleaner(t)
+
+	result, err := cleaner.Delete(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Failures) > 0 {
+		t.Fatalf("unexpected failures: %v", result.Failures)
+	}
+	if names := appNames(result.Deleted); len(names) != 1 || names[0] != "idle" {
+		t.Fatalf("expected idle to be deleted, got %v", names)
+	}
+	if _, err := radixClient.RadixV1().RadixRegistrations().Get(ctx, "idle", metav1.GetOptions{}); !kubeerrors.IsNotFound(err) {
+		t.Errorf("expected idle to be deleted, got %v", err)
+	}
+	if _, err := radixClient.RadixV1().RadixRegistrations().Get(ctx, "busy", metav1.GetOptions{}); err != nil {
+		t.Errorf("expected busy to remain, got %v", err)
+	}
+}
+
+func TestDeleteKeepsMarkMissingFromStaleResources(t *testing.T) {
+	ctx := context.Background()
+	var stale *inactivity.Resources
+	cleaner, radixClient := newCleanerWith(t, func(options *cleanup.Options) {
+		options.NoBackup = true
+		options.DeletionGracePeriod = 7 * day
+		options.ListResources = func(context.Context) (*inactivity.Resources, error) {
+			return stale, nil
+		}
+	})
+	var err error
+	if stale, err = inactivity.ListResources(ctx, radixClient); err != nil {
+		t.Fatal(err)
+	}
+	// An earlier run marked idle after the resources were listed, like in an informer cache not yet updated
+	markedAt := now.Add(-3 * day).Format(time.RFC3339)
+	rr, err := radixClient.RadixV1().RadixRegistrations().Get(ctx, "idle", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr.Annotations = map[string]string{"radix.equinor.com/cleanup-marked-for-deletion-at": markedAt}
+	if _, err := radixClient.RadixV1().RadixRegistrations().Update(ctx, rr, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := cleaner.Delete(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Pending) != 1 || !result.Pending[0].DeleteAfter.Equal(now.Add(4*day)) {
+		t.Fatalf("expected idle to be deleted 7 days after the earlier mark, got %v", result.Pending)
+	}
+	if rr, err = radixClient.RadixV1().RadixRegistrations().Get(ctx, "idle", metav1.GetOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if actual := rr.Annotations["radix.equinor.com/cleanup-marked-for-deletion-at"]; actual != markedAt {
+		t.Errorf("expected the mark %s to be kept, got %s", markedAt, actual)
+	}
+}
+
+func TestDryRunDeletesPastGracePeriod(t *testing.T) {
+	ctx := context.Background()
+	// idle was last active 60 days ago, and went past the deletion threshold of 28 days 32 days ago
+	tests := []struct {
+		gracePeriod time.Duration
+		deleted     bool
+	}{
+		{gracePeriod: 7 * day, deleted: true},
+		{gracePeriod: 60 * day, deleted: false},
+	}
+	for _, test := range tests {
+		cleaner, _ := newCleanerWith(t, func(options *cleanup.Options) {
+			options.NoBackup = true
+			options.DryRun = true
+			options.DeletionGracePeriod = test.gracePeriod
+		})
+		result, err := cleaner.Delete(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.deleted {
+			if names := appNames(result.Deleted); len(names) != 1 || names[0] != "idle" {
+				t.Errorf("expected idle to be deleted with a grace period of %s, got %v", test.gracePeriod, names)
+			}
+			continue
+		}
+		if len(result.Deleted) > 0 || len(result.Pending) != 1 {
+			t.Fatalf("expected idle to be pending with a grace period of %s, got deleted %v, pending %v", test.gracePeriod, appNames(result.Deleted), result.Pending)
+		}
+		if deleteAfter := now.Add(-32 * day).Add(test.gracePeriod); !result.Pending[0].DeleteAfter.Equal(deleteAfter) {
+			t.Errorf("expected idle to be deleted after %s, got %s", deleteAfter, result.Pending[0].DeleteAfter)
+		}
+	}
+}
+
+func TestDeleteRequiresBackupOrNoBackup(t *testing.T) {
+	ctx := context.Background()
+	cleaner, radixClient := newCleanerWith(t, func(*cleanup.Options) {})
+
+	if _, err := cleaner.Delete(ctx); err == nil {
+		t.Fatal("expected Delete to refuse to run without a backup sink or NoBackup")
+	}
+	if _, err := radixClient.RadixV1().RadixRegistrations().Get(ctx, "idle", metav1.GetOptions{}); err != nil {
+		t.Errorf("expected idle to remain, got %v", err)
+	}
+}
+
+func TestDeleteBacksUpSecretMetadata(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	secret := &metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Name: "web-secrets", Namespace: "idle-prod"},
+	}
+	scheme := metadatafake.NewTestScheme()
+	if err := metav1.AddMetaToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	cleaner, _ := newCleanerWith(t, func(options *cleanup.Options) {
+		options.BackupSink = backup.NewDirSink(dir)
+		options.MetadataClient = metadatafake.NewSimpleMetadataClient(scheme, secret)
+	})
+
+	result, err := cleaner.Delete(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Failures) > 0 {
+		t.Fatalf("unexpected failures: %v", result.Failures)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "idle", "*.json"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected one backup of idle, got %v, %v", files, err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rrBackup backup.Backup
+	if err := json.Unmarshal(data, &rrBackup); err != nil {
+		t.Fatal(err)
+	}
+	if len(rrBackup.Secrets) != 1 || rrBackup.Secrets[0].Name != "web-secrets" {
+		t.Errorf("expected the backup to name secret web-secrets, got %v", rrBackup.Secrets)
+	}
+}
+
+func TestStopStartsNoAppsWhenShuttingDown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cleaner, radixClient := newCleaner(t)
+	runCtx, cancelRun := cleanup.WithGracePeriod(ctx, time.Minute)
+	defer cancelRun()
+	cancel()
+
+	if runCtx.Err() != nil {
+		t.Fatal("expected the run to get the grace period to finish")
+	}
+	result, err := cleaner.Stop(runCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Interrupted || len(result.Stopped) > 0 {
+		t.Fatalf("expected an interrupted run stopping nothing, got %+v", result)
+	}
+	rd, err := radixClient.RadixV1().RadixDeployments("idle-prod").Get(context.Background(), "prod-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replicas := rd.Spec.Components[0].ReplicasOverride; replicas != nil {
+		t.Errorf("expected replicasOverride to be unset, got %d", *replicas)
+	}
+}
+
+func TestGracePeriodCancelsRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	runCtx, cancelRun := cleanup.WithGracePeriod(ctx, 10*time.Millisecond)
+	defer cancelRun()
+	cancel()
+
+	select {
+	case <-runCtx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the run to be cancelled after the grace period")
+	}
+}
@@ -0,0 +1,36 @@
+package cleanup
+
+import (
+	"github.com/equinor/radix-cluster-cleanup/pkg/inactivity"
+	"golang.org/x/sync/errgroup"
+)
+
+// forEachConcurrently calls fn for each index below count, with at most concurrency calls running at the same time,
+// and returns when all calls are done. Results should be stored by index to keep them in a deterministic order.
+func forEachConcurrently(concurrency, count int, fn func(i int)) {
+	var group errgroup.Group
+	group.SetLimit(concurrency)
+	for i := range count {
+		group.Go(func() error {
+			fn(i)
+			return nil
+		})
+	}
+	_ = group.Wait()
+}
+
+// groupByApp groups RadixRegistrations and environments by application, in the order the applications first appear
+func groupByApp(inactiveRrs []inactivity.InactiveRr) [][]inactivity.InactiveRr {
+	var groups [][]inactivity.InactiveRr
+	groupIndex := make(map[string]int)
+	for _, inactiveRr := range inactiveRrs {
+		i, ok := groupIndex[inactiveRr.Rr.Name]
+		if !ok {
+			i = len(groups)
+			groupIndex[inactiveRr.Rr.Name] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], inactiveRr)
+	}
+	return groups
+}
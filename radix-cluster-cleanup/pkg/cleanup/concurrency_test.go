@@ -0,0 +1,40 @@
+package cleanup
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestForEachConcurrentlyLimitsConcurrency(t *testing.T) {
+	const concurrency, count = 3, 10
+	var running, maxRunning atomic.Int32
+	var called [count]atomic.Bool
+	// The calls block until as many as allowed run at the same time
+	limitReached := make(chan struct{})
+	var signalLimitReached sync.Once
+	forEachConcurrently(concurrency, count, func(i int) {
+		current := running.Add(1)
+		defer running.Add(-1)
+		for {
+			previous := maxRunning.Load()
+			if current <= previous || maxRunning.CompareAndSwap(previous, current) {
+				break
+			}
+		}
+		if current == concurrency {
+			signalLimitReached.Do(func() { close(limitReached) })
+		}
+		<-limitReached
+		called[i].Store(true)
+	})
+
+	if actual := maxRunning.Load(); actual != concurrency {
+		t.Errorf("expected at most %d calls at the same time, got %d", concurrency, actual)
+	}
+	for i := range called {
+		if !called[i].Load() {
+			t.Errorf("expected call %d", i)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package cleanup
+
+import (
+	"context"
+	"time"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/inactivity"
+	"github.com/equinor/radix-cluster-cleanup/pkg/metrics"
+	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	"github.com/rs/zerolog/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Delete warns the owners of RadixRegistrations approaching the deletion threshold, removes the marks of
+// RadixRegistrations with new activity, marks those past it, and backs up and deletes those marked for the grace
+// period, if the circuit breaker allows.
+// Errors of individual applications are returned in the result, other errors abort the run.
+// When shutting down, the RadixRegistration in progress is deleted, but no more.
+func (c *Cleaner) Delete(ctx context.Context) (*DeleteResult, error) {
+	if c.options.BackupSink == nil && !c.options.NoBackup {
+		return nil, errNoBackup
+	}
+	if shuttingDown(ctx) {
+		return &DeleteResult{Interrupted: true}, nil
+	}
+	action := inactivity.ActionDeletion
+	resources, err := c.listResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+	failures := newFailures()
+	evaluated := c.evaluate(ctx, resources, action, failures)
+	result := &DeleteResult{Due: evaluated.Due}
+	result.Warned = c.warnRrs(ctx, evaluated.Warn, action, failures)
+	metrics.SetRrsMarked(action, len(result.Due))
+	result.Unmarked = c.unmarkReactivatedRrs(ctx, resources, result.Due, action, failures)
+	if shuttingDown(ctx) {
+		result.Interrupted = true
+		result.Failures = failures.list()
+		return result, nil
+	}
+	if err := c.allowAction(ctx, action, c.countDueForDeletion(result.Due, action), len(resources.RadixRegistrations())); err != nil {
+		return nil, err
+	}
+	for _, inactiveRr := range result.Due {
+		if shuttingDown(ctx) {
+			break
+		}
+		ctx := log.Ctx(ctx).With().Str("appName", inactiveRr.Rr.Name).Logger().WithContext(ctx)
+		markedAt, newlyMarked, err := c.markRr(ctx, inactiveRr, action)
+		if err != nil {
+			failures.add(ctx, inactiveRr.Rr.Name, "", metrics.ErrorKindDelete, err)
+			continue
+		}
+		if newlyMarked {
+			markedAt = c.markedForDeletionAt(inactiveRr, action)
+		}
+		if deleteAfter := markedAt.Add(c.options.DeletionGracePeriod); c.now().Before(deleteAfter) {
+			log.Ctx(ctx).Info().Msgf("RadixRegistration is marked for deletion, deleting after %s", deleteAfter.Format(time.RFC3339))
+			result.Pending = append(result.Pending, PendingDeletion{InactiveRr: inactiveRr, DeleteAfter: deleteAfter})
+			continue
+		}
+		if err := c.deleteRr(ctx, resources, inactiveRr.Rr); err != nil {
+			failures.add(ctx, inactiveRr.Rr.Name, "", metrics.ErrorKindDelete, err)
+			continue
+		}
+		result.Deleted = append(result.Deleted, inactiveRr)
+	}
+	result.Failures = failures.list()
+	result.Interrupted = shuttingDown(ctx)
+	return result, nil
+}
+
+// countDueForDeletion returns the number of RadixRegistrations which have been marked for deletion for the grace period,
+// or will be when marked now
+func (c *Cleaner) countDueForDeletion(inactiveRrs []inactivity.InactiveRr, action string) int {
+	now := c.now()
+	count := 0
+	for _, inactiveRr := range inactiveRrs {
+		if !now.Before(c.markedForDeletionAt(inactiveRr, action).Add(c.options.DeletionGracePeriod)) {
+			count++
+		}
+	}
+	return count
+}
+
+// markedForDeletionAt returns when a RadixRegistration was marked for deletion, or is marked by this run if it was not.
+// A dry-run does not persist marks, so it would never get past the grace period. It takes RadixRegistrations not
+// marked as marked when they went past their inactivity limit instead, to report those a cleanup running all along
+// would delete now.
+func (c *Cleaner) markedForDeletionAt(inactiveRr inactivity.InactiveRr, action string) time.Time {
+	if markedAt, ok := GetMarkedTimestamp(inactiveRr, action); ok {
+		return markedAt
+	}
+	now := c.now().UTC().Truncate(time.Second)
+	if !c.options.DryRun {
+		return now
+	}
+	if pastLimitAt := inactiveRr.Activity.LastActivity.Add(inactiveRr.InactivityLimit); pastLimitAt.Before(now) {
+		return pastLimitAt
+	}
+	return now
+}
+
+// deleteRr backs up, writes a tombstone for and deletes a RadixRegistration. It is not deleted if either fails.
+func (c *Cleaner) deleteRr(ctx context.Context, resources *inactivity.Resources, rr v1.RadixRegistration) error {
+	if err := c.backupRr(ctx, resources, rr); err != nil {
+		metrics.AddError(metrics.ErrorKindBackup)
+		return err
+	}
+	if err := c.writeTombstone(ctx, resources, rr); err != nil {
+		metrics.AddError(metrics.ErrorKindBackup)
+		return err
+	}
+	err := c.radixClient.RadixV1().RadixRegistrations().Delete(ctx, rr.Name, metav1.DeleteOptions{DryRun: c.dryRunOption()})
+	if err != nil {
+		return err
+	}
+	if c.options.DryRun {
+		log.Info().Str("appName", rr.Name).Msg("dry-run: would delete RadixRegistration")
+		return nil
+	}
+	metrics.AddRrDeleted()
+	log.Info().Str("appName", rr.Name).Msg("Deleted RadixRegistration")
+	return nil
+}
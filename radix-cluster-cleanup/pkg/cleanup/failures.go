@@ -0,0 +1,88 @@
+package cleanup
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+// Failure is an application, or one of its environments, which failed in a run. One failing application does not
+// stop the cleanup of the others.
+type Failure struct {
+	AppName     string
+	Environment string
+	// Kind is the metrics error kind, e.g. stop or delete
+	Kind string
+	Err  error
+}
+
+func (f Failure) String() string {
+	if f.Environment != "" {
+		return fmt.Sprintf("%s/%s: %v", f.AppName, f.Environment, f.Err)
+	}
+	return fmt.Sprintf("%s: %v", f.AppName, f.Err)
+}
+
+// failures collects the failures of a run, possibly added concurrently
+type failures struct {
+	mu       sync.Mutex
+	failures []Failure
+}
+
+func newFailures() *failures {
+	return &failures{}
+}
+
+// add records and logs the failure of an application, or one of its environments, and counts it by error kind.
+// The logger in the context is expected to carry the app name and environment.
+func (f *failures) add(ctx context.Context, appName, environment, kind string, err error) {
+	metrics.AddError(kind)
+	log.Ctx(ctx).Error().Err(err).Msgf("failed to %s, continuing with the next application", strings.ReplaceAll(kind, "_", " "))
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures = append(f.failures, Failure{AppName: appName, Environment: environment, Kind: kind, Err: err})
+}
+
+// failed returns true if the application, or any of its environments, failed in this run
+func (f *failures) failed(appName string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, failure := range f.failures {
+		if failure.AppName == appName {
+			return true
+		}
+	}
+	return false
+}
+
+// list returns the failures sorted by application and environment, as they may have been added concurrently
+func (f *failures) list() []Failure {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sorted := slices.Clone(f.failures)
+	slices.SortStableFunc(sorted, func(a, b Failure) int {
+		return cmp.Or(cmp.Compare(a.AppName, b.AppName), cmp.Compare(a.Environment, b.Environment))
+	})
+	return sorted
+}
+
+// Summarize logs a summary of the failures in a run, and returns them joined in one error, or nil if there were none
+func Summarize(ctx context.Context, action string, failures []Failure) error {
+	if len(failures) == 0 {
+		return nil
+	}
+	errs := make([]error, 0, len(failures))
+	for _, failure := range failures {
+		errs = append(errs, errors.New(failure.String()))
+	}
+	err := fmt.Errorf("%d failures during %s run: %w", len(failures), action, errors.Join(errs...))
+	log.Ctx(ctx).Error().Err(err).Int("failures", len(failures)).Msgf("%s run completed with failures", action)
+	return err
+}
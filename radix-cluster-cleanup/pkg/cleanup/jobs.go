@@ -0,0 +1,158 @@
+package cleanup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/metrics"
+	"github.com/equinor/radix-common/utils/pointers"
+	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	"github.com/rs/zerolog/log"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const jobSchedulerReplicasAnnotation = "radix.equinor.com/cleanup-job-scheduler-replicas"
+
+// recordJobSchedulerReplicas adds the current replicas of each job scheduler, if not already recorded, to the
+// annotations of the RadixDeployment, so they can be restored
+func (c *Cleaner) recordJobSchedulerReplicas(ctx context.Context, rd *v1.RadixDeployment) error {
+	if len(rd.Spec.Jobs) == 0 {
+		return nil
+	}
+	originalReplicas, err := getOriginalJobSchedulerReplicas(*rd)
+	if err != nil {
+		return err
+	}
+	for _, job := range rd.Spec.Jobs {
+		if _, ok := originalReplicas[job.Name]; ok {
+			continue
+		}
+		scale, err := c.kubeClient.AppsV1().Deployments(rd.Namespace).GetScale(ctx, job.Name, metav1.GetOptions{})
+		if kubeerrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		originalReplicas[job.Name] = scale.Spec.Replicas
+	}
+	value, err := json.Marshal(originalReplicas)
+	if err != nil {
+		return err
+	}
+	if rd.Annotations == nil {
+		rd.Annotations = make(map[string]string)
+	}
+	rd.Annotations[jobSchedulerReplicasAnnotation] = string(value)
+	return nil
+}
+
+// getOriginalJobSchedulerReplicas returns the replicas of each job scheduler before the RadixDeployment was first
+// stopped, or an empty map if it has not been stopped
+func getOriginalJobSchedulerReplicas(rd v1.RadixDeployment) (map[string]int32, error) {
+	originalReplicas := make(map[string]int32)
+	value, ok := rd.Annotations[jobSchedulerReplicasAnnotation]
+	if !ok {
+		return originalReplicas, nil
+	}
+	if err := json.Unmarshal([]byte(value), &originalReplicas); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation on RadixDeployment %s: %w", jobSchedulerReplicasAnnotation, rd.Name, err)
+	}
+	return originalReplicas, nil
+}
+
+// stopRdJobComponents scales the job scheduler of each job component to zero replicas, and stops the jobs in its
+// outstanding batches, and returns whether any job component changed.
+// The stopped batches stay stopped, as the operator honours stop in the RadixBatch spec. The scale-down is not durable:
+// the RadixDeployment has no field for job scheduler replicas, so the operator scales the job scheduler back up the
+// next time it reconciles the RadixDeployment. Inactive environments are stopped again on every run, which scales it
+// down again, but it runs between the operator's reconcile and the next run.
+func (c *Cleaner) stopRdJobComponents(ctx context.Context, rd v1.RadixDeployment) (bool, error) {
+	if len(rd.Spec.Jobs) == 0 {
+		return false, nil
+	}
+	logger := log.Ctx(ctx)
+	dryRun := c.options.DryRun
+	jobNames := make([]string, 0, len(rd.Spec.Jobs))
+	for _, job := range rd.Spec.Jobs {
+		scaled, err := c.scaleJobScheduler(ctx, rd.Namespace, job.Name, 0)
+		if err != nil {
+			return false, err
+		}
+		stoppedBatches, err := c.stopOutstandingBatches(ctx, rd.Namespace, job.Name)
+		if err != nil {
+			return false, err
+		}
+		if !scaled && stoppedBatches == 0 {
+			continue
+		}
+		logger.Info().Bool("dryRun", dryRun).Str("jobComponent", job.Name).Msgf("scaled down job scheduler until the next reconcile by the operator, and stopped %d outstanding batches", stoppedBatches)
+		jobNames = append(jobNames, job.Name)
+	}
+	if len(jobNames) == 0 {
+		return false, nil
+	}
+	if dryRun {
+		logger.Info().Msgf("dry-run: would stop the batches and scale down the job schedulers of job components %s", strings.Join(jobNames, ", "))
+		return true, nil
+	}
+	metrics.AddJobComponentsStopped(len(jobNames))
+	logger.Info().Msgf("stopped the batches and scaled down the job schedulers of job components %s", strings.Join(jobNames, ", "))
+	return true, nil
+}
+
+// scaleJobScheduler sets the replicas of the job scheduler deployment for a job component, and returns whether they
+// changed
+func (c *Cleaner) scaleJobScheduler(ctx context.Context, namespace, jobName string, replicas int32) (bool, error) {
+	deployments := c.kubeClient.AppsV1().Deployments(namespace)
+	scale, err := deployments.GetScale(ctx, jobName, metav1.GetOptions{})
+	if kubeerrors.IsNotFound(err) {
+		log.Ctx(ctx).Debug().Str("jobComponent", jobName).Msg("job scheduler deployment not found, skipping")
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if scale.Spec.Replicas == replicas {
+		return false, nil
+	}
+	scale.Spec.Replicas = replicas
+	if _, err = deployments.UpdateScale(ctx, jobName, scale, metav1.UpdateOptions{DryRun: c.dryRunOption()}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// stopOutstandingBatches sets stop on every job in batches for a job component which have not completed, and
+// returns the number of batches stopped
+func (c *Cleaner) stopOutstandingBatches(ctx context.Context, namespace, jobName string) (int, error) {
+	batches, err := c.radixClient.RadixV1().RadixBatches(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return 0, err
+	}
+	stoppedBatches := 0
+	for _, batch := range batches.Items {
+		if batch.Spec.RadixDeploymentJobRef.Job != jobName || batch.Status.Condition.Type == v1.BatchConditionTypeCompleted {
+			continue
+		}
+		changed := false
+		for i := range batch.Spec.Jobs {
+			if batch.Spec.Jobs[i].Stop == nil || !*batch.Spec.Jobs[i].Stop {
+				batch.Spec.Jobs[i].Stop = pointers.Ptr(true)
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		if _, err := c.radixClient.RadixV1().RadixBatches(namespace).Update(ctx, &batch, metav1.UpdateOptions{DryRun: c.dryRunOption()}); err != nil {
+			return stoppedBatches, err
+		}
+		log.Ctx(ctx).Debug().Str("jobComponent", jobName).Msgf("stopped batch %s", batch.Name)
+		stoppedBatches++
+	}
+	return stoppedBatches, nil
+}
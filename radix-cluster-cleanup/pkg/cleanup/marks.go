@@ -0,0 +1,110 @@
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/inactivity"
+	"github.com/equinor/radix-cluster-cleanup/pkg/metrics"
+	"github.com/equinor/radix-common/utils/pointers"
+	"github.com/rs/zerolog/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const markedAnnotationPrefix = "radix.equinor.com/cleanup-marked-for-"
+
+// markedAnnotation returns the annotation marking a RadixRegistration, or one of its environments, for an action
+func markedAnnotation(action, environment string) string {
+	if environment == "" {
+		return fmt.Sprintf("%s%s-at", markedAnnotationPrefix, action)
+	}
+	return fmt.Sprintf("%s%s-in-%s-at", markedAnnotationPrefix, action, environment)
+}
+
+// GetMarkedTimestamp returns when the RadixRegistration or environment was marked for an action, if it carries a valid mark
+func GetMarkedTimestamp(inactiveRr inactivity.InactiveRr, action string) (time.Time, bool) {
+	markedAt, ok := inactiveRr.Rr.Annotations[markedAnnotation(action, inactiveRr.Activity.Environment)]
+	if !ok {
+		return time.Time{}, false
+	}
+	timestamp, err := time.Parse(time.RFC3339, markedAt)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return timestamp, true
+}
+
+// countNewlyMarked returns the number of RadixRegistrations or environments not already marked for an action
+func countNewlyMarked(inactiveRrs []inactivity.InactiveRr, action string) int {
+	count := 0
+	for _, inactiveRr := range inactiveRrs {
+		if _, ok := GetMarkedTimestamp(inactiveRr, action); !ok {
+			count++
+		}
+	}
+	return count
+}
+
+// markRr sets the mark annotation for an action on a RadixRegistration or environment not already marked, and returns
+// when it was marked, and whether it was marked by this call.
+// The evaluated RadixRegistration may come from a cache which has not seen a mark set by an earlier run, so the latest
+// version is read before marking, and the mark is only set if the RadixRegistration has not changed since.
+func (c *Cleaner) markRr(ctx context.Context, inactiveRr inactivity.InactiveRr, action string) (time.Time, bool, error) {
+	if markedAt, ok := GetMarkedTimestamp(inactiveRr, action); ok {
+		return markedAt, false, nil
+	}
+	rr, err := c.radixClient.RadixV1().RadixRegistrations().Get(ctx, inactiveRr.Rr.Name, metav1.GetOptions{})
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	latest := inactiveRr
+	latest.Rr = *rr
+	if markedAt, ok := GetMarkedTimestamp(latest, action); ok {
+		return markedAt, false, nil
+	}
+	markedAt := c.now().UTC().Truncate(time.Second)
+	annotation := markedAnnotation(action, inactiveRr.Activity.Environment)
+	if err := c.annotateRr(ctx, rr.Name, rr.ResourceVersion, map[string]*string{annotation: pointers.Ptr(markedAt.Format(time.RFC3339))}); err != nil {
+		return time.Time{}, false, err
+	}
+	log.Ctx(ctx).Info().Bool("dryRun", c.options.DryRun).Msgf("marked RadixRegistration for %s", action)
+	return markedAt, true, nil
+}
+
+// unmarkReactivatedRrs removes the marks for an action from RadixRegistrations and environments which are no longer too inactive.
+// Applications which failed earlier in the run are left as they are, as they may not have been evaluated.
+func (c *Cleaner) unmarkReactivatedRrs(ctx context.Context, resources *inactivity.Resources, rrsForAction []inactivity.InactiveRr, action string, failures *failures) []string {
+	activeMarks := make(map[string]struct{}, len(rrsForAction))
+	for _, inactiveRr := range rrsForAction {
+		activeMarks[inactiveRr.Rr.Name+"/"+markedAnnotation(action, inactiveRr.Activity.Environment)] = struct{}{}
+	}
+	actionPrefix := fmt.Sprintf("%s%s-", markedAnnotationPrefix, action)
+	var unmarked []string
+	for _, rr := range resources.RadixRegistrations() {
+		if failures.failed(rr.Name) {
+			continue
+		}
+		staleMarks := make(map[string]*string)
+		for annotation := range rr.Annotations {
+			if !strings.HasPrefix(annotation, actionPrefix) {
+				continue
+			}
+			if _, ok := activeMarks[rr.Name+"/"+annotation]; !ok {
+				staleMarks[annotation] = nil
+			}
+		}
+		if len(staleMarks) == 0 {
+			continue
+		}
+		logger := log.Ctx(ctx).With().Str("appName", rr.Name).Logger()
+		if err := c.annotateRr(logger.WithContext(ctx), rr.Name, "", staleMarks); err != nil {
+			failures.add(logger.WithContext(ctx), rr.Name, "", metrics.ErrorKindUnmark, err)
+			continue
+		}
+		unmarked = append(unmarked, rr.Name)
+		logger.Info().Bool("dryRun", c.options.DryRun).Msgf("RadixRegistration has new activity, removed mark for %s", action)
+	}
+	return unmarked
+}
@@ -0,0 +1,115 @@
+package cleanup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/inactivity"
+	"github.com/equinor/radix-cluster-cleanup/pkg/metrics"
+	"github.com/equinor/radix-cluster-cleanup/pkg/notifier"
+	"github.com/equinor/radix-common/utils/pointers"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// warnedAnnotation returns the annotation recording a warning about an action on a RadixRegistration, or one of its environments
+func warnedAnnotation(action, environment string) string {
+	if environment == "" {
+		return fmt.Sprintf("radix.equinor.com/cleanup-warned-for-%s-at", action)
+	}
+	return fmt.Sprintf("radix.equinor.com/cleanup-warned-for-%s-in-%s-at", action, environment)
+}
+
+// splitRrsForWarning separates RadixRegistrations which have passed their inactivity limit from those which are
+// only within the warning period before it
+func splitRrsForWarning(inactiveRrs []inactivity.InactiveRr) ([]inactivity.InactiveRr, []inactivity.InactiveRr) {
+	var rrsForAction, rrsForWarning []inactivity.InactiveRr
+	for _, inactiveRr := range inactiveRrs {
+		if !inactiveRr.WarnOnly && inactiveRr.PastLimit {
+			rrsForAction = append(rrsForAction, inactiveRr)
+		} else {
+			rrsForWarning = append(rrsForWarning, inactiveRr)
+		}
+	}
+	return rrsForAction, rrsForWarning
+}
+
+// warnRrs notifies the owners of RadixRegistrations approaching the inactivity limit, and records the warning in an
+// annotation on the RadixRegistration so the owners are only warned once per inactivity period.
+// It returns the RadixRegistrations warned, and adds those which fail to be warned to failures.
+func (c *Cleaner) warnRrs(ctx context.Context, rrsForWarning []inactivity.InactiveRr, action string, failures *failures) []inactivity.InactiveRr {
+	var warned []inactivity.InactiveRr
+	for _, inactiveRr := range rrsForWarning {
+		if shuttingDown(ctx) {
+			break
+		}
+		logger := inactiveRr.Logger(ctx)
+		ctx := logger.WithContext(ctx)
+		if alreadyWarned(inactiveRr, action) {
+			logger.Debug().Msgf("owners are already warned about %s", action)
+			continue
+		}
+		if c.options.Notifier == nil {
+			logger.Info().Msgf("policy rule %s only warns about %s, but warnings are disabled", inactiveRr.Activity.PolicyRule, action)
+			continue
+		}
+		notification := notifier.Notification{
+			AppName:           inactiveRr.Rr.Name,
+			Environment:       inactiveRr.Activity.Environment,
+			Action:            action,
+			Owner:             inactiveRr.Rr.Spec.Owner,
+			AdGroups:          inactiveRr.Rr.Spec.AdGroups,
+			ConfigurationItem: inactiveRr.Rr.Spec.ConfigurationItem,
+			LastActivity:      inactiveRr.Activity.LastActivity.Time,
+			DaysInactive:      inactiveRr.Activity.DaysInactive,
+			ActionAt:          inactiveRr.Activity.LastActivity.Add(inactiveRr.InactivityLimit),
+		}
+		if c.options.DryRun {
+			logger.Info().Msgf("dry-run: would warn owners about %s at %s", action, notification.ActionAt.Format(time.RFC3339))
+			warned = append(warned, inactiveRr)
+			continue
+		}
+		if err := c.options.Notifier.Notify(ctx, notification); err != nil {
+			failures.add(ctx, inactiveRr.Rr.Name, inactiveRr.Activity.Environment, metrics.ErrorKindNotify, fmt.Errorf("failed to warn owners: %w", err))
+			continue
+		}
+		if err := c.annotateRr(ctx, inactiveRr.Rr.Name, "", map[string]*string{warnedAnnotation(action, inactiveRr.Activity.Environment): pointers.Ptr(c.now().UTC().Format(time.RFC3339))}); err != nil {
+			failures.add(ctx, inactiveRr.Rr.Name, inactiveRr.Activity.Environment, metrics.ErrorKindNotify, err)
+			continue
+		}
+		metrics.AddRrWarned(action)
+		warned = append(warned, inactiveRr)
+		logger.Info().Msgf("warned owners about %s at %s", action, notification.ActionAt.Format(time.RFC3339))
+	}
+	return warned
+}
+
+// alreadyWarned returns true if the owners were warned after the last activity of the RadixRegistration
+func alreadyWarned(inactiveRr inactivity.InactiveRr, action string) bool {
+	warnedAt, ok := inactiveRr.Rr.Annotations[warnedAnnotation(action, inactiveRr.Activity.Environment)]
+	if !ok {
+		return false
+	}
+	timestamp, err := time.Parse(time.RFC3339, warnedAt)
+	if err != nil {
+		return false
+	}
+	return timestamp.After(inactiveRr.Activity.LastActivity.Time)
+}
+
+// annotateRr sets or, for nil values, removes annotations on a RadixRegistration with a merge patch. With a
+// resourceVersion, the patch fails with a conflict if the RadixRegistration has changed since that version.
+func (c *Cleaner) annotateRr(ctx context.Context, rrName, resourceVersion string, annotations map[string]*string) error {
+	metadata := map[string]any{"annotations": annotations}
+	if resourceVersion != "" {
+		metadata["resourceVersion"] = resourceVersion
+	}
+	patch, err := json.Marshal(map[string]any{"metadata": metadata})
+	if err != nil {
+		return err
+	}
+	_, err = c.radixClient.RadixV1().RadixRegistrations().Patch(ctx, rrName, types.MergePatchType, patch, metav1.PatchOptions{DryRun: c.dryRunOption()})
+	return err
+}
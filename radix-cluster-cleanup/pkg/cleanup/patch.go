@@ -0,0 +1,93 @@
+package cleanup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	"github.com/rs/zerolog/log"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+)
+
+// jsonPatchOperation is an RFC 6902 JSON patch operation
+type jsonPatchOperation struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Value any    `json:"value"`
+}
+
+// patchRadixDeployment applies a JSON patch built from the latest version of a RadixDeployment.
+// The patch is expected to test the parts of the RadixDeployment it depends on, like component names at the indices
+// it changes. If the RadixDeployment changed between get and patch so a test fails, or the patch conflicts, the patch
+// is rebuilt from the latest version and retried with backoff.
+func (c *Cleaner) patchRadixDeployment(ctx context.Context, namespace, name string, buildPatch func(rd v1.RadixDeployment) ([]jsonPatchOperation, error)) error {
+	rds := c.radixClient.RadixV1().RadixDeployments(namespace)
+	retriable := func(err error) bool {
+		// A failed test operation is rejected as invalid
+		return kubeerrors.IsConflict(err) || kubeerrors.IsInvalid(err)
+	}
+	attempt := 0
+	return retry.OnError(retry.DefaultBackoff, retriable, func() error {
+		if attempt++; attempt > 1 {
+			log.Ctx(ctx).Debug().Str("deployment", name).Msgf("RadixDeployment changed while patching, retrying (attempt %d)", attempt)
+		}
+		rd, err := rds.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		operations, err := buildPatch(*rd)
+		if err != nil || len(operations) == 0 {
+			return err
+		}
+		patch, err := json.Marshal(operations)
+		if err != nil {
+			return err
+		}
+		_, err = rds.Patch(ctx, name, types.JSONPatchType, patch, metav1.PatchOptions{DryRun: c.dryRunOption()})
+		return err
+	})
+}
+
+// componentReplicasOverrideOperations returns operations testing the name of the component at an index, and setting
+// or, for nil, removing its replicasOverride
+func componentReplicasOverrideOperations(index int, component v1.RadixDeployComponent, replicasOverride *int) []jsonPatchOperation {
+	path := fmt.Sprintf("/spec/components/%d", index)
+	operations := []jsonPatchOperation{{Op: "test", Path: path + "/name", Value: component.Name}}
+	if replicasOverride == nil {
+		if component.ReplicasOverride == nil {
+			return nil
+		}
+		return append(operations, jsonPatchOperation{Op: "remove", Path: path + "/replicasOverride"})
+	}
+	return append(operations, jsonPatchOperation{Op: "add", Path: path + "/replicasOverride", Value: *replicasOverride})
+}
+
+// annotationOperations returns the operations changing the given annotation keys from their values in before to
+// their values in after, removing keys missing in after
+func annotationOperations(before, after map[string]string, keys ...string) []jsonPatchOperation {
+	var operations []jsonPatchOperation
+	for _, key := range keys {
+		beforeValue, inBefore := before[key]
+		afterValue, inAfter := after[key]
+		path := "/metadata/annotations/" + escapeJsonPointer(key)
+		switch {
+		case inAfter && (!inBefore || beforeValue != afterValue):
+			operations = append(operations, jsonPatchOperation{Op: "add", Path: path, Value: afterValue})
+		case inBefore && !inAfter:
+			operations = append(operations, jsonPatchOperation{Op: "remove", Path: path})
+		}
+	}
+	if before == nil && len(operations) > 0 {
+		operations = append([]jsonPatchOperation{{Op: "add", Path: "/metadata/annotations", Value: map[string]string{}}}, operations...)
+	}
+	return operations
+}
+
+func escapeJsonPointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
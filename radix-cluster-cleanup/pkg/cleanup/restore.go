@@ -0,0 +1,146 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/inactivity"
+	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	"github.com/rs/zerolog/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Restore restores the active RadixDeployments stopped by the cleanup, optionally limited to one application, and to
+// those stopped after a point in time, and returns the RadixDeployments restored. With a point in time, RadixDeployments
+// without a valid record of when they were stopped are skipped.
+// A RadixDeployment failing to be restored does not stop the others from being restored, and the errors are returned
+// joined, together with the RadixDeployments restored.
+func (c *Cleaner) Restore(ctx context.Context, appName string, stoppedAfter time.Time) ([]v1.RadixDeployment, error) {
+	rds, err := c.getStoppedRadixDeployments(ctx, appName)
+	if err != nil {
+		return nil, err
+	}
+	var restored []v1.RadixDeployment
+	var errs []error
+	for _, rd := range rds {
+		logger := log.Ctx(ctx).With().Str("appName", rd.Spec.AppName).Str("environment", rd.Spec.Environment).Str("deployment", rd.Name).Logger()
+		ctx := logger.WithContext(ctx)
+		if !rdIsActive(rd) {
+			logger.Debug().Msg("RadixDeployment is no longer active, skipping")
+			continue
+		}
+		if !stoppedAfter.IsZero() {
+			stoppedAt, err := time.Parse(time.RFC3339, rd.Annotations[stoppedAtAnnotation])
+			if err != nil {
+				logger.Warn().Err(err).Msgf("RadixDeployment has no valid %s annotation to compare with the time it must be stopped after, skipping", stoppedAtAnnotation)
+				continue
+			}
+			if stoppedAt.Before(stoppedAfter) {
+				logger.Debug().Msgf("RadixDeployment was stopped %s, skipping", stoppedAt.Format(time.RFC3339))
+				continue
+			}
+		}
+		if err := c.restoreRd(ctx, rd); err != nil {
+			logger.Error().Err(err).Msg("failed to restore RadixDeployment")
+			errs = append(errs, fmt.Errorf("failed to restore RadixDeployment %s/%s: %w", rd.Namespace, rd.Name, err))
+			continue
+		}
+		restored = append(restored, rd)
+	}
+	return restored, errors.Join(errs...)
+}
+
+// getStoppedRadixDeployments returns the RadixDeployments of an application, or of all applications if appName is
+// empty, which carry the replicas recorded when they were stopped
+func (c *Cleaner) getStoppedRadixDeployments(ctx context.Context, appName string) ([]v1.RadixDeployment, error) {
+	var rds []v1.RadixDeployment
+	if appName != "" {
+		ra, err := c.getRadixApplication(ctx, appName)
+		if err != nil {
+			return nil, err
+		}
+		if rds, err = c.getRadixDeploymentsInNamespaces(ctx, inactivity.RuntimeNamespaces(ra)); err != nil {
+			return nil, err
+		}
+	} else {
+		rdList, err := c.radixClient.RadixV1().RadixDeployments(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		rds = rdList.Items
+	}
+	var stoppedRds []v1.RadixDeployment
+	for _, rd := range rds {
+		if _, ok := rd.Annotations[replicasOverrideAnnotation]; ok {
+			stoppedRds = append(stoppedRds, rd)
+		}
+	}
+	return stoppedRds, nil
+}
+
+// restoreRd patches back the recorded replicasOverride of each component still stopped, and removes the recording
+func (c *Cleaner) restoreRd(ctx context.Context, rd v1.RadixDeployment) error {
+	logger := log.Ctx(ctx)
+	dryRun := c.options.DryRun
+	originalJobSchedulerReplicas, err := getOriginalJobSchedulerReplicas(rd)
+	if err != nil {
+		return err
+	}
+	var restoredComponents []string
+	err = c.patchRadixDeployment(ctx, rd.Namespace, rd.Name, func(rd v1.RadixDeployment) ([]jsonPatchOperation, error) {
+		restoredComponents = restoredComponents[:0]
+		originalReplicasOverride, err := getOriginalReplicasOverride(rd)
+		if err != nil {
+			return nil, err
+		}
+		var operations []jsonPatchOperation
+		for i, component := range rd.Spec.Components {
+			original, ok := originalReplicasOverride[component.Name]
+			if !ok {
+				continue
+			}
+			if component.ReplicasOverride == nil || *component.ReplicasOverride != 0 {
+				logger.Info().Str("component", component.Name).Msgf("replicasOverride has changed to %s since stop, leaving it", formatReplicasOverride(component.ReplicasOverride))
+				continue
+			}
+			operations = append(operations, componentReplicasOverrideOperations(i, component, original)...)
+			restoredComponents = append(restoredComponents, fmt.Sprintf("%s=%s", component.Name, formatReplicasOverride(original)))
+		}
+		annotations := maps.Clone(rd.Annotations)
+		delete(annotations, replicasOverrideAnnotation)
+		delete(annotations, jobSchedulerReplicasAnnotation)
+		delete(annotations, stoppedAtAnnotation)
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		annotations[inactivity.LastUserMutationAnnotation] = c.now().UTC().Format(time.RFC3339)
+		operations = append(operations, annotationOperations(rd.Annotations, annotations, replicasOverrideAnnotation, jobSchedulerReplicasAnnotation, stoppedAtAnnotation, inactivity.LastUserMutationAnnotation)...)
+		return operations, nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(restoredComponents) > 0 {
+		logger.Info().Bool("dryRun", dryRun).Msgf("changed replicasOverride from 0 to %s", strings.Join(restoredComponents, ", "))
+	}
+	for _, job := range rd.Spec.Jobs {
+		replicas, ok := originalJobSchedulerReplicas[job.Name]
+		if !ok {
+			continue
+		}
+		scaled, err := c.scaleJobScheduler(ctx, rd.Namespace, job.Name, replicas)
+		if err != nil {
+			return err
+		}
+		if !scaled {
+			continue
+		}
+		logger.Info().Bool("dryRun", dryRun).Str("jobComponent", job.Name).Msgf("scaled job scheduler to %d replicas", replicas)
+	}
+	logger.Info().Bool("dryRun", dryRun).Msg("restored RadixDeployment")
+	return nil
+}
@@ -0,0 +1,32 @@
+package cleanup
+
+import (
+	"context"
+	"time"
+)
+
+type shutdownKey struct{}
+
+// WithGracePeriod returns a context for a run which is not cancelled when ctx is. When ctx is cancelled, Stop and
+// Delete finish the application in progress, but start no new ones, and the returned context is cancelled after the
+// grace period in case they have not returned by then.
+func WithGracePeriod(ctx context.Context, gracePeriod time.Duration) (context.Context, context.CancelFunc) {
+	runCtx, cancel := context.WithCancel(context.WithValue(context.WithoutCancel(ctx), shutdownKey{}, ctx))
+	stopAfterFunc := context.AfterFunc(ctx, func() {
+		time.AfterFunc(gracePeriod, cancel)
+	})
+	return runCtx, func() {
+		stopAfterFunc()
+		cancel()
+	}
+}
+
+// shuttingDown tells if the run should start no more applications, as its context, or the context given to
+// WithGracePeriod, is cancelled
+func shuttingDown(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return true
+	}
+	parent, ok := ctx.Value(shutdownKey{}).(context.Context)
+	return ok && parent.Err() != nil
+}
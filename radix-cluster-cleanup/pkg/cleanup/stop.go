@@ -0,0 +1,209 @@
+package cleanup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/inactivity"
+	"github.com/equinor/radix-cluster-cleanup/pkg/metrics"
+	"github.com/equinor/radix-common/utils/pointers"
+	"github.com/equinor/radix-common/utils/slice"
+	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	"github.com/equinor/radix-operator/pkg/apis/utils"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	replicasOverrideAnnotation = "radix.equinor.com/cleanup-replicas-override"
+	stoppedAtAnnotation        = "radix.equinor.com/cleanup-stopped-at"
+)
+
+// Stop warns the owners of environments approaching the stop threshold, removes the marks of environments with new
+// activity, and stops the environments past it, if the circuit breaker allows.
+// Errors of individual applications are returned in the result, other errors abort the run.
+// When shutting down, environments of the application in progress are stopped, but no more applications.
+func (c *Cleaner) Stop(ctx context.Context) (*StopResult, error) {
+	if shuttingDown(ctx) {
+		return &StopResult{Interrupted: true}, nil
+	}
+	action := inactivity.ActionStop
+	resources, err := c.listResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+	failures := newFailures()
+	evaluated := c.evaluate(ctx, resources, action, failures)
+	result := &StopResult{Due: evaluated.Due}
+	result.Warned = c.warnRrs(ctx, evaluated.Warn, action, failures)
+	metrics.SetRrsMarked(action, CountApps(result.Due))
+	metrics.SetEnvironmentsMarked(action, len(result.Due))
+	result.Unmarked = c.unmarkReactivatedRrs(ctx, resources, result.Due, action, failures)
+	if shuttingDown(ctx) {
+		result.Interrupted = true
+		result.Failures = failures.list()
+		return result, nil
+	}
+	if err := c.allowAction(ctx, action, countNewlyMarked(result.Due, action), countRunningEnvironments(resources)); err != nil {
+		return nil, err
+	}
+
+	// Environments of the same application are stopped one at a time, as they are marked on the same RadixRegistration
+	rrsByApp := groupByApp(result.Due)
+	stopped := make([][]inactivity.InactiveRr, len(rrsByApp))
+	forEachConcurrently(c.options.Concurrency, len(rrsByApp), func(i int) {
+		if shuttingDown(ctx) {
+			return
+		}
+		for _, inactiveRr := range rrsByApp[i] {
+			ctx := inactiveRr.Logger(ctx).WithContext(ctx)
+			if _, _, err := c.markRr(ctx, inactiveRr, action); err != nil {
+				failures.add(ctx, inactiveRr.Rr.Name, inactiveRr.Activity.Environment, metrics.ErrorKindStop, err)
+				continue
+			}
+			if err := c.stopEnvironment(ctx, resources, inactiveRr.Rr.Name, inactiveRr.Activity.Environment); err != nil {
+				failures.add(ctx, inactiveRr.Rr.Name, inactiveRr.Activity.Environment, metrics.ErrorKindStop, err)
+				continue
+			}
+			stopped[i] = append(stopped[i], inactiveRr)
+		}
+	})
+	result.Stopped = slices.Concat(stopped...)
+	result.Failures = failures.list()
+	result.Interrupted = shuttingDown(ctx)
+	return result, nil
+}
+
+// stopEnvironment scales all components in the active RadixDeployment of an environment to zero replicas, stops
+// outstanding batches, and scales down the job schedulers until the operator next reconciles the RadixDeployment.
+// An environment already stopped is stopped again in every run, but only counted as stopped if anything changed.
+func (c *Cleaner) stopEnvironment(ctx context.Context, resources *inactivity.Resources, appName, environment string) error {
+	rdsForEnv := resources.RadixDeployments(utils.GetEnvironmentNamespace(appName, environment))
+	changed := false
+	for _, rd := range slice.FindAll(rdsForEnv, rdIsActive) {
+		ctx := log.Ctx(ctx).With().Str("deployment", rd.Name).Logger().WithContext(ctx)
+		scaledComponents, err := c.scaleRdComponentsToZeroReplicas(ctx, rd)
+		if err != nil {
+			return err
+		}
+		stoppedJobComponents, err := c.stopRdJobComponents(ctx, rd)
+		if err != nil {
+			return err
+		}
+		changed = changed || scaledComponents || stoppedJobComponents
+	}
+	if changed && !c.options.DryRun {
+		metrics.AddEnvironmentStopped()
+	}
+	return nil
+}
+
+// CountApps returns the number of distinct applications among RadixRegistrations and environments
+func CountApps(inactiveRrs []inactivity.InactiveRr) int {
+	appNames := make(map[string]struct{})
+	for _, inactiveRr := range inactiveRrs {
+		appNames[inactiveRr.Rr.Name] = struct{}{}
+	}
+	return len(appNames)
+}
+
+// scaleRdComponentsToZeroReplicas patches replicasOverride of the components in a RadixDeployment not already at 0 to
+// 0, recording the original values and job scheduler replicas in annotations, and returns whether any component was
+// scaled. Nothing is patched if nothing changed.
+func (c *Cleaner) scaleRdComponentsToZeroReplicas(ctx context.Context, rd v1.RadixDeployment) (bool, error) {
+	logger := log.Ctx(ctx)
+	dryRun := c.options.DryRun
+	var changedComponents []v1.RadixDeployComponent
+	err := c.patchRadixDeployment(ctx, rd.Namespace, rd.Name, func(rd v1.RadixDeployment) ([]jsonPatchOperation, error) {
+		changedComponents = changedComponents[:0]
+		originalReplicasOverride, err := getOriginalReplicasOverride(rd)
+		if err != nil {
+			return nil, err
+		}
+		var operations []jsonPatchOperation
+		for i, component := range rd.Spec.Components {
+			if component.ReplicasOverride != nil && *component.ReplicasOverride == 0 {
+				continue
+			}
+			if _, ok := originalReplicasOverride[component.Name]; !ok {
+				originalReplicasOverride[component.Name] = component.ReplicasOverride
+			}
+			operations = append(operations, componentReplicasOverrideOperations(i, component, pointers.Ptr(0))...)
+			changedComponents = append(changedComponents, component)
+		}
+		annotations := rd.Annotations
+		rd.Annotations = maps.Clone(annotations)
+		if err := c.setOriginalReplicasOverride(&rd, originalReplicasOverride); err != nil {
+			return nil, err
+		}
+		if err := c.recordJobSchedulerReplicas(ctx, &rd); err != nil {
+			return nil, err
+		}
+		operations = append(operations, annotationOperations(annotations, rd.Annotations, replicasOverrideAnnotation, jobSchedulerReplicasAnnotation, stoppedAtAnnotation)...)
+		return operations, nil
+	})
+	if err != nil {
+		return false, err
+	}
+	if len(changedComponents) == 0 {
+		return false, nil
+	}
+	componentNames := make([]string, 0, len(changedComponents))
+	for _, component := range changedComponents {
+		logger.Info().Bool("dryRun", dryRun).Str("component", component.Name).Msgf("changed replicasOverride from %s to 0", formatReplicasOverride(component.ReplicasOverride))
+		componentNames = append(componentNames, component.Name)
+	}
+	if dryRun {
+		logger.Info().Msgf("dry-run: would scale components %s to 0 replicas", strings.Join(componentNames, ", "))
+		return true, nil
+	}
+	metrics.AddComponentsStopped(len(componentNames))
+	logger.Info().Msgf("scaled components %s to 0 replicas", strings.Join(componentNames, ", "))
+	return true, nil
+}
+
+// getOriginalReplicasOverride returns the replicasOverride of each component before the RadixDeployment was first
+// stopped, or an empty map if it has not been stopped
+func getOriginalReplicasOverride(rd v1.RadixDeployment) (map[string]*int, error) {
+	originalReplicasOverride := make(map[string]*int)
+	value, ok := rd.Annotations[replicasOverrideAnnotation]
+	if !ok {
+		return originalReplicasOverride, nil
+	}
+	if err := json.Unmarshal([]byte(value), &originalReplicasOverride); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation on RadixDeployment %s: %w", replicasOverrideAnnotation, rd.Name, err)
+	}
+	return originalReplicasOverride, nil
+}
+
+// setOriginalReplicasOverride records the original replicasOverride of each component, and when it was first stopped
+func (c *Cleaner) setOriginalReplicasOverride(rd *v1.RadixDeployment, originalReplicasOverride map[string]*int) error {
+	value, err := json.Marshal(originalReplicasOverride)
+	if err != nil {
+		return err
+	}
+	if rd.Annotations == nil {
+		rd.Annotations = make(map[string]string)
+	}
+	rd.Annotations[replicasOverrideAnnotation] = string(value)
+	if _, ok := rd.Annotations[stoppedAtAnnotation]; !ok {
+		rd.Annotations[stoppedAtAnnotation] = c.now().UTC().Format(time.RFC3339)
+	}
+	return nil
+}
+
+func formatReplicasOverride(replicasOverride *int) string {
+	if replicasOverride == nil {
+		return "unset"
+	}
+	return strconv.Itoa(*replicasOverride)
+}
+
+func rdIsActive(rd v1.RadixDeployment) bool {
+	return rd.Status.Condition == v1.DeploymentActive
+}
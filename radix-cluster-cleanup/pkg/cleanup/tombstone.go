@@ -0,0 +1,47 @@
+package cleanup
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/inactivity"
+	"github.com/equinor/radix-cluster-cleanup/pkg/tombstone"
+	"github.com/equinor/radix-common/utils/slice"
+	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	"github.com/rs/zerolog/log"
+)
+
+// writeTombstone records the RadixRegistration, its RadixApplication and latest build-deploy RadixJob in the tombstone
+// store.
+// The RadixRegistration must not be deleted if an error is returned.
+func (c *Cleaner) writeTombstone(ctx context.Context, resources *inactivity.Resources, rr v1.RadixRegistration) error {
+	logger := log.Ctx(ctx)
+	store := c.options.TombstoneStore
+	if store == nil {
+		logger.Debug().Msg("no tombstone store configured, deleting RadixRegistration without tombstone")
+		return nil
+	}
+	ra := resources.RadixApplication(rr.Name)
+	rj := latestBuildDeployJob(resources.RadixJobs(rr.Name))
+	if c.options.DryRun {
+		logger.Info().Msg("dry-run: would write tombstone")
+		return nil
+	}
+	if err := store.Put(ctx, tombstone.New(rr, ra, rj, c.now())); err != nil {
+		return fmt.Errorf("failed to write tombstone for %s: %w", rr.Name, err)
+	}
+	logger.Info().Msg("wrote tombstone")
+	return nil
+}
+
+// latestBuildDeployJob returns the latest build-deploy RadixJob, or nil if there is none
+func latestBuildDeployJob(rjs []v1.RadixJob) *v1.RadixJob {
+	buildDeployJobs := slice.FindAll(rjs, func(rj v1.RadixJob) bool {
+		return rj.Spec.PipeLineType == v1.BuildDeploy
+	})
+	if len(buildDeployJobs) == 0 {
+		return nil
+	}
+	sortedJobs := inactivity.SortJobsByTimestampAsc(buildDeployJobs)
+	return &sortedJobs[len(sortedJobs)-1]
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+)
+
+// EnvPrefix is the prefix of environment variables setting options
+const EnvPrefix = "RX_CLEANUP_"
+
+// Source is where the effective value of an option came from
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// Value is the effective value of an option, and where it came from
+type Value struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source Source `json:"source"`
+	// Origin is the file or environment variable the value was read from
+	Origin string `json:"origin,omitempty"`
+}
+
+// EnvName returns the environment variable setting an option, e.g. RX_CLEANUP_INACTIVE_DAYS_BEFORE_STOP for
+// inactive-days-before-stop
+func EnvName(option string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(option, "-", "_"))
+}
+
+// LoadFile reads a YAML file mapping option names to values. Lists are joined with commas, as for flags.
+func LoadFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	values := make(map[string]string, len(raw))
+	for name, value := range raw {
+		switch v := value.(type) {
+		case nil:
+			continue
+		case []any:
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				items = append(items, fmt.Sprint(item))
+			}
+			values[name] = strings.Join(items, ",")
+		case map[string]any:
+			return nil, fmt.Errorf("invalid value for %s in config file %s: nested objects are not supported", name, path)
+		default:
+			values[name] = fmt.Sprint(v)
+		}
+	}
+	return values, nil
+}
+
+// Apply sets each flag not given on the command line from its environment variable or, if not set, from the config
+// file, and returns the effective value of every flag with its source, sorted by name.
+// Flags in skip are left out.
+func Apply(flags *pflag.FlagSet, fileValues map[string]string, filePath string, lookupEnv func(string) (string, bool), skip ...string) ([]Value, error) {
+	var values []Value
+	var errs []string
+	flags.VisitAll(func(flag *pflag.Flag) {
+		for _, name := range skip {
+			if flag.Name == name {
+				return
+			}
+		}
+		value := Value{Name: flag.Name, Source: SourceDefault}
+		if flag.Changed {
+			value.Source = SourceFlag
+		} else if envValue, ok := lookupEnv(EnvName(flag.Name)); ok {
+			value.Source, value.Origin = SourceEnv, EnvName(flag.Name)
+			if err := flags.Set(flag.Name, envValue); err != nil {
+				errs = append(errs, fmt.Sprintf("invalid value %q in %s: %v", envValue, value.Origin, err))
+			}
+		} else if fileValue, ok := fileValues[flag.Name]; ok {
+			value.Source, value.Origin = SourceFile, filePath
+			if err := flags.Set(flag.Name, fileValue); err != nil {
+				errs = append(errs, fmt.Sprintf("invalid value %q for %s in %s: %v", fileValue, flag.Name, filePath, err))
+			}
+		}
+		value.Value = formatValue(flag)
+		values = append(values, value)
+	})
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].Name < values[j].Name })
+	return values, nil
+}
+
+func formatValue(flag *pflag.Flag) string {
+	if sliceValue, ok := flag.Value.(pflag.SliceValue); ok {
+		return strings.Join(sliceValue.GetSlice(), ",")
+	}
+	return flag.Value.String()
+}
@@ -0,0 +1,83 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/config"
+	"github.com/spf13/pflag"
+)
+
+const option = "inactive-days-before-stop"
+
+func TestApplyPrecedence(t *testing.T) {
+	tests := []struct {
+		name           string
+		file           map[string]string
+		env            map[string]string
+		args           []string
+		expectedValue  string
+		expectedSource config.Source
+		expectedOrigin string
+	}{
+		{name: "default", expectedValue: "7", expectedSource: config.SourceDefault},
+		{name: "file overrides default", file: map[string]string{option: "14"}, expectedValue: "14", expectedSource: config.SourceFile, expectedOrigin: "config.yaml"},
+		{name: "env overrides file", file: map[string]string{option: "14"}, env: map[string]string{"RX_CLEANUP_INACTIVE_DAYS_BEFORE_STOP": "21"}, expectedValue: "21", expectedSource: config.SourceEnv, expectedOrigin: "RX_CLEANUP_INACTIVE_DAYS_BEFORE_STOP"},
+		{name: "flag overrides env", file: map[string]string{option: "14"}, env: map[string]string{"RX_CLEANUP_INACTIVE_DAYS_BEFORE_STOP": "21"}, args: []string{"--" + option, "28"}, expectedValue: "28", expectedSource: config.SourceFlag},
+		{name: "unknown keys in the file are left for other commands", file: map[string]string{"inactive-days-before-deletion": "60"}, expectedValue: "7", expectedSource: config.SourceDefault},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			flags.Int(option, 7, "")
+			if err := flags.Parse(test.args); err != nil {
+				t.Fatal(err)
+			}
+			lookupEnv := func(name string) (string, bool) {
+				value, ok := test.env[name]
+				return value, ok
+			}
+			values, err := config.Apply(flags, test.file, "config.yaml", lookupEnv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := config.Value{Name: option, Value: test.expectedValue, Source: test.expectedSource, Origin: test.expectedOrigin}
+			if len(values) != 1 || values[0] != expected {
+				t.Errorf("expected %+v, got %+v", expected, values)
+			}
+			if actual := flags.Lookup(option).Value.String(); actual != test.expectedValue {
+				t.Errorf("expected the flag to be set to %s, got %s", test.expectedValue, actual)
+			}
+		})
+	}
+}
+
+func TestApplyRejectsInvalidValues(t *testing.T) {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.Int(option, 7, "")
+	if _, err := config.Apply(flags, map[string]string{option: "a week"}, "config.yaml", func(string) (string, bool) { return "", false }); err == nil {
+		t.Error("expected an invalid value in the file to be an error")
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("inactive-days-before-stop: 14\ncleanup-days: [mo, tu]\nwhitelist:\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	values, err := config.LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 2 || values["inactive-days-before-stop"] != "14" || values["cleanup-days"] != "mo,tu" {
+		t.Errorf("expected numbers as strings and lists joined with commas, got %v", values)
+	}
+
+	if err := os.WriteFile(path, []byte("inactive-days-before-stop:\n  days: 14\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.LoadFile(path); err == nil {
+		t.Error("expected a nested object to be an error")
+	}
+}
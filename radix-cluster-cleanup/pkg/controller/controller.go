@@ -0,0 +1,352 @@
+// Package controller runs the cleanup on events from informers on RadixRegistrations, RadixDeployments and RadixJobs,
+// and on a resync interval, in the replica holding a Lease. The cleanup evaluates the resources in the informer caches,
+// so the cluster is listed once when the controller starts leading.
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/inactivity"
+	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	radixclient "github.com/equinor/radix-operator/pkg/client/clientset/versioned"
+	"github.com/rs/zerolog/log"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/clock"
+)
+
+// reconcileKey is the only key in the queue. A reconcile covers the whole cluster, so the circuit breaker limits all
+// stops and deletions of a run together, and events arriving while one is queued are merged into it.
+const reconcileKey = "cluster"
+
+// Window tells if the cleanup may take actions at a point in time
+type Window interface {
+	Contains(t time.Time) bool
+}
+
+// Options configures a Controller
+type Options struct {
+	// Reconcile stops and deletes inactive RadixRegistrations in the resources returned by listResources, which come
+	// from the informer caches and must not be modified
+	Reconcile func(ctx context.Context, listResources inactivity.ListFunc) error
+	// Window is when Reconcile may run. Events outside it are dropped, and picked up by the next resync inside it.
+	Window Window
+	// Resync is how often Reconcile runs without events
+	Resync time.Duration
+	// Debounce is how long after an event Reconcile runs, so a burst of events leads to one run
+	Debounce time.Duration
+	// LeaseNamespace and LeaseName are the Lease the replicas elect a leader with
+	LeaseNamespace string
+	LeaseName      string
+	// Identity is the holder of the Lease while this replica leads, e.g. the pod name
+	Identity string
+	// LeaseDuration is how long other replicas wait before taking over the Lease of a leader which stopped renewing it
+	LeaseDuration time.Duration
+	// Clock tells the time for the window, debounce and resync. Defaults to the system clock.
+	Clock clock.WithTicker
+}
+
+// Controller runs Reconcile on events and on the resync interval, while it is the leader
+type Controller struct {
+	kubeClient  kubernetes.Interface
+	radixClient radixclient.Interface
+	options     Options
+	clock       clock.WithTicker
+	queue       workqueue.TypedDelayingInterface[string]
+}
+
+// informers cache the resources the cleanup evaluates
+type informers struct {
+	rrs, ras, rds, rjs cache.SharedIndexInformer
+}
+
+// New returns a Controller watching the cluster of the clients
+func New(kubeClient kubernetes.Interface, radixClient radixclient.Interface, options Options) (*Controller, error) {
+	if options.Reconcile == nil {
+		return nil, fmt.Errorf("reconcile is required")
+	}
+	if options.Resync <= 0 {
+		return nil, fmt.Errorf("resync interval must be positive")
+	}
+	if options.LeaseNamespace == "" || options.LeaseName == "" || options.Identity == "" {
+		return nil, fmt.Errorf("lease namespace, name and identity are required for leader election")
+	}
+	if options.LeaseDuration <= 0 {
+		return nil, fmt.Errorf("lease duration must be positive")
+	}
+	controllerClock := options.Clock
+	if controllerClock == nil {
+		controllerClock = clock.RealClock{}
+	}
+	return &Controller{
+		kubeClient:  kubeClient,
+		radixClient: radixClient,
+		options:     options,
+		clock:       controllerClock,
+		queue: workqueue.NewTypedDelayingQueueWithConfig(workqueue.TypedDelayingQueueConfig[string]{
+			Name:  "radix-cluster-cleanup",
+			Clock: controllerClock,
+		}),
+	}, nil
+}
+
+// Run campaigns for the Lease, and reconciles while it is the leader, until the context is cancelled. The Lease is
+// released on cancel, so another replica can take over without waiting for it to expire.
+// An error is returned if leadership is lost before the context is cancelled, as the informers and queue can not be
+// restarted.
+func (c *Controller) Run(ctx context.Context) error {
+	logger := log.Ctx(ctx).With().Str("identity", c.options.Identity).Logger()
+	ctx = logger.WithContext(ctx)
+	defer c.queue.ShutDown()
+
+	lock, err := resourcelock.New(resourcelock.LeasesResourceLock, c.options.LeaseNamespace, c.options.LeaseName,
+		c.kubeClient.CoreV1(), c.kubeClient.CoordinationV1(), resourcelock.ResourceLockConfig{Identity: c.options.Identity})
+	if err != nil {
+		return err
+	}
+	lostLeadership := false
+	// leading receives the context of the leadership, so the controller runs in this goroutine, and has returned when
+	// Run returns
+	leading := make(chan context.Context, 1)
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   c.options.LeaseDuration,
+		RenewDeadline:   c.options.LeaseDuration * 2 / 3,
+		RetryPeriod:     c.options.LeaseDuration / 5,
+		ReleaseOnCancel: true,
+		Name:            c.options.LeaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				leading <- ctx
+			},
+			OnStoppedLeading: func() {
+				lostLeadership = ctx.Err() == nil
+				logger.Info().Msgf("released Lease %s/%s", c.options.LeaseNamespace, c.options.LeaseName)
+			},
+			OnNewLeader: func(identity string) {
+				if identity != c.options.Identity {
+					logger.Info().Msgf("%s is the leader, waiting", identity)
+				}
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	electorDone := make(chan struct{})
+	go func() {
+		defer close(electorDone)
+		elector.Run(ctx)
+	}()
+	select {
+	case <-electorDone:
+	case leaderCtx := <-leading:
+		logger.Info().Msgf("acquired Lease %s/%s, starting controller", c.options.LeaseNamespace, c.options.LeaseName)
+		if err := c.lead(leaderCtx); err != nil {
+			logger.Error().Err(err).Msg("controller failed")
+		}
+		<-electorDone
+	}
+	if lostLeadership {
+		return fmt.Errorf("lost Lease %s/%s", c.options.LeaseNamespace, c.options.LeaseName)
+	}
+	return nil
+}
+
+// lead starts the informers, and reconciles on events and the resync interval until the context is cancelled
+func (c *Controller) lead(ctx context.Context) error {
+	cleanupInformers := informers{
+		rrs: c.newInformer(&v1.RadixRegistration{}, func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+			return c.radixClient.RadixV1().RadixRegistrations().List(ctx, opts)
+		}, func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+			return c.radixClient.RadixV1().RadixRegistrations().Watch(ctx, opts)
+		}),
+		ras: c.newInformer(&v1.RadixApplication{}, func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+			return c.radixClient.RadixV1().RadixApplications(metav1.NamespaceAll).List(ctx, opts)
+		}, func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+			return c.radixClient.RadixV1().RadixApplications(metav1.NamespaceAll).Watch(ctx, opts)
+		}),
+		rds: c.newInformer(&v1.RadixDeployment{}, func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+			return c.radixClient.RadixV1().RadixDeployments(metav1.NamespaceAll).List(ctx, opts)
+		}, func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+			return c.radixClient.RadixV1().RadixDeployments(metav1.NamespaceAll).Watch(ctx, opts)
+		}),
+		rjs: c.newInformer(&v1.RadixJob{}, func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+			return c.radixClient.RadixV1().RadixJobs(metav1.NamespaceAll).List(ctx, opts)
+		}, func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+			return c.radixClient.RadixV1().RadixJobs(metav1.NamespaceAll).Watch(ctx, opts)
+		}),
+	}
+	// RadixApplications are only cached, as a change to one is followed by a new RadixDeployment when it is deployed
+	for _, informer := range []cache.SharedIndexInformer{cleanupInformers.rrs, cleanupInformers.rds, cleanupInformers.rjs} {
+		if _, err := informer.AddEventHandler(c.eventHandler(ctx)); err != nil {
+			return err
+		}
+	}
+	var synced []cache.InformerSynced
+	for _, informer := range []cache.SharedIndexInformer{cleanupInformers.rrs, cleanupInformers.ras, cleanupInformers.rds, cleanupInformers.rjs} {
+		go informer.RunWithContext(ctx)
+		synced = append(synced, informer.HasSynced)
+	}
+	if !cache.WaitForNamedCacheSyncWithContext(ctx, synced...) {
+		return ctx.Err()
+	}
+
+	go c.resync(ctx)
+	for c.processNext(ctx, cleanupInformers.listResources) {
+	}
+	return nil
+}
+
+// newInformer returns an informer without managed fields in its cache, as the cleanup does not read them
+func (c *Controller) newInformer(exampleObject runtime.Object, list cache.ListWithContextFunc, watch cache.WatchFuncWithContext) cache.SharedIndexInformer {
+	listWatch := cache.ToListWatcherWithWatchListSemantics(&cache.ListWatch{ListWithContextFunc: list, WatchFuncWithContext: watch}, c.radixClient)
+	informer := cache.NewSharedIndexInformer(listWatch, exampleObject, 0, cache.Indexers{})
+	_ = informer.SetTransform(stripManagedFields)
+	return informer
+}
+
+func stripManagedFields(obj any) (any, error) {
+	if objMeta, err := metaAccessor(obj); err == nil {
+		objMeta.SetManagedFields(nil)
+	}
+	return obj, nil
+}
+
+// listResources returns the resources in the informer caches
+func (i informers) listResources(context.Context) (*inactivity.Resources, error) {
+	return inactivity.NewResources(listStore[v1.RadixRegistration](i.rrs), listStore[v1.RadixApplication](i.ras),
+		listStore[v1.RadixDeployment](i.rds), listStore[v1.RadixJob](i.rjs)), nil
+}
+
+func listStore[T any](informer cache.SharedIndexInformer) []*T {
+	objects := informer.GetStore().List()
+	typed := make([]*T, 0, len(objects))
+	for _, obj := range objects {
+		if object, ok := obj.(*T); ok {
+			typed = append(typed, object)
+		}
+	}
+	return typed
+}
+
+// eventHandler queues a reconcile when a RadixRegistration is created, changed or deleted, or a RadixDeployment or
+// RadixJob is created or changed, as these are new activity.
+// Changes to metadata only do not change the generation and are ignored, so the marks and warnings the cleanup sets on
+// RadixRegistrations do not queue another reconcile. Nor do changes to only the replicasOverride of components, so
+// stopping and restoring RadixDeployments does not either.
+func (c *Controller) eventHandler(ctx context.Context) cache.ResourceEventHandlerDetailedFuncs {
+	return cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj any, isInInitialList bool) {
+			if !isInInitialList {
+				c.enqueue(ctx, "created", obj)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj any) {
+			oldMeta, oldErr := metaAccessor(oldObj)
+			newMeta, newErr := metaAccessor(newObj)
+			if oldErr == nil && newErr == nil && oldMeta.GetGeneration() == newMeta.GetGeneration() {
+				return
+			}
+			if replicasOverrideChangedOnly(oldObj, newObj) {
+				return
+			}
+			c.enqueue(ctx, "changed", newObj)
+		},
+		DeleteFunc: func(obj any) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if _, ok := obj.(*v1.RadixRegistration); ok {
+				c.enqueue(ctx, "deleted", obj)
+			}
+		},
+	}
+}
+
+// replicasOverrideChangedOnly tells if two versions of a RadixDeployment differ in nothing but the replicasOverride of
+// their components and metadata
+func replicasOverrideChangedOnly(oldObj, newObj any) bool {
+	oldRd, oldOk := oldObj.(*v1.RadixDeployment)
+	newRd, newOk := newObj.(*v1.RadixDeployment)
+	if !oldOk || !newOk {
+		return false
+	}
+	return equality.Semantic.DeepEqual(withoutReplicasOverride(oldRd.Spec), withoutReplicasOverride(newRd.Spec)) &&
+		equality.Semantic.DeepEqual(oldRd.Status, newRd.Status)
+}
+
+// withoutReplicasOverride returns a copy of a RadixDeployment spec with the replicasOverride of each component unset
+func withoutReplicasOverride(spec v1.RadixDeploymentSpec) v1.RadixDeploymentSpec {
+	components := make([]v1.RadixDeployComponent, len(spec.Components))
+	for i, component := range spec.Components {
+		component.ReplicasOverride = nil
+		components[i] = component
+	}
+	spec.Components = components
+	return spec
+}
+
+func (c *Controller) enqueue(ctx context.Context, event string, obj any) {
+	if objMeta, err := metaAccessor(obj); err == nil {
+		log.Ctx(ctx).Debug().Str("namespace", objMeta.GetNamespace()).Str("name", objMeta.GetName()).Msgf("%T %s, queueing reconcile", obj, event)
+	}
+	c.queue.AddAfter(reconcileKey, c.options.Debounce)
+}
+
+func metaAccessor(obj any) (metav1.Object, error) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	objMeta, ok := obj.(metav1.Object)
+	if !ok {
+		return nil, fmt.Errorf("%T has no object metadata", obj)
+	}
+	return objMeta, nil
+}
+
+// resync queues a reconcile right away, and every resync interval after that
+func (c *Controller) resync(ctx context.Context) {
+	ticker := c.clock.NewTicker(c.options.Resync)
+	defer ticker.Stop()
+	for {
+		c.queue.Add(reconcileKey)
+		select {
+		case <-ctx.Done():
+			c.queue.ShutDown()
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// processNext reconciles the next queued key, if the window is open. A failed reconcile is retried on the next resync,
+// or event. It returns false when the queue is shut down.
+func (c *Controller) processNext(ctx context.Context, listResources inactivity.ListFunc) bool {
+	key, shutdown := c.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.queue.Done(key)
+	logger := log.Ctx(ctx)
+
+	now := c.clock.Now()
+	if c.options.Window != nil && !c.options.Window.Contains(now) {
+		logger.Info().Msgf("%s is outside of window, waiting for the next resync", now)
+		return true
+	}
+	logger.Info().Msg("reconciling")
+	if err := c.options.Reconcile(ctx, listResources); err != nil {
+		logger.Error().Err(err).Msg("reconcile failed, retrying in the next resync")
+	}
+	return true
+}
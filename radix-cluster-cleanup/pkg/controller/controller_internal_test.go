@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/equinor/radix-common/utils/pointers"
+	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestReplicasOverrideChangedOnly(t *testing.T) {
+	rd := &v1.RadixDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "prod-1", Namespace: "app-prod", Generation: 1},
+		Spec:       v1.RadixDeploymentSpec{Components: []v1.RadixDeployComponent{{Name: "web"}}},
+		Status:     v1.RadixDeployStatus{Condition: v1.DeploymentActive},
+	}
+	tests := []struct {
+		name     string
+		change   func(rd *v1.RadixDeployment)
+		expected bool
+	}{
+		{name: "stopped", expected: true, change: func(rd *v1.RadixDeployment) {
+			rd.Spec.Components[0].ReplicasOverride = pointers.Ptr(0)
+			rd.Annotations = map[string]string{"radix.equinor.com/cleanup-stopped-at": "2026-06-01T00:00:00Z"}
+		}},
+		{name: "component added", expected: false, change: func(rd *v1.RadixDeployment) {
+			rd.Spec.Components = append(rd.Spec.Components, v1.RadixDeployComponent{Name: "api"})
+		}},
+		{name: "status changed", expected: false, change: func(rd *v1.RadixDeployment) {
+			rd.Status.Condition = v1.DeploymentInactive
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			changed := rd.DeepCopy()
+			changed.Generation++
+			test.change(changed)
+			if actual := replicasOverrideChangedOnly(rd, changed); actual != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, actual)
+			}
+		})
+	}
+	if replicasOverrideChangedOnly(&v1.RadixRegistration{}, &v1.RadixRegistration{}) {
+		t.Error("expected other kinds than RadixDeployment to never be ignored")
+	}
+}
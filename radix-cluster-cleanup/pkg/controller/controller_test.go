@@ -0,0 +1,147 @@
+package controller_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/controller"
+	"github.com/equinor/radix-cluster-cleanup/pkg/inactivity"
+	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	radixfake "github.com/equinor/radix-operator/pkg/client/clientset/versioned/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+const resync = time.Hour
+
+// fixedWindow is open or closed at all times, and sends each time it is checked, as the controller checks it for every
+// queued reconcile
+type fixedWindow struct {
+	open   bool
+	checks chan time.Time
+}
+
+func (w fixedWindow) Contains(t time.Time) bool {
+	w.checks <- t
+	return w.open
+}
+
+type testController struct {
+	radixClient *radixfake.Clientset
+	clock       *testingclock.FakeClock
+	checks      chan time.Time
+	// reconciles receives the names of the RadixRegistrations each reconcile listed
+	reconciles chan []string
+}
+
+// startController runs a controller until the test ends
+func startController(t *testing.T, open bool, objects ...runtime.Object) *testController {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	test := &testController{
+		radixClient: radixfake.NewSimpleClientset(objects...),
+		clock:       testingclock.NewFakeClock(time.Date(2026, time.June, 1, 12, 0, 0, 0, time.UTC)),
+		checks:      make(chan time.Time, 10),
+		reconciles:  make(chan []string, 10),
+	}
+	cleanupController, err := controller.New(kubefake.NewSimpleClientset(), test.radixClient, controller.Options{
+		Reconcile: func(ctx context.Context, listResources inactivity.ListFunc) error {
+			resources, err := listResources(ctx)
+			if err != nil {
+				return err
+			}
+			var names []string
+			for _, rr := range resources.RadixRegistrations() {
+				names = append(names, rr.Name)
+			}
+			test.reconciles <- names
+			return nil
+		},
+		Window:         fixedWindow{open: open, checks: test.checks},
+		Resync:         resync,
+		LeaseNamespace: "radix-cluster-cleanup",
+		LeaseName:      "radix-cluster-cleanup",
+		Identity:       "test",
+		LeaseDuration:  time.Second,
+		Clock:          test.clock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() {
+		done <- cleanupController.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	})
+	return test
+}
+
+func receive[T any](t *testing.T, ch <-chan T, what string) T {
+	t.Helper()
+	select {
+	case value := <-ch:
+		return value
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+		var zero T
+		return zero
+	}
+}
+
+func (test *testController) createRr(t *testing.T, name string) {
+	t.Helper()
+	rr := &v1.RadixRegistration{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if _, err := test.radixClient.RadixV1().RadixRegistrations().Create(context.Background(), rr, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertReconciled(t *testing.T, expected, actual []string) {
+	t.Helper()
+	if !slices.Equal(expected, actual) {
+		t.Errorf("expected reconcile of %v, got %v", expected, actual)
+	}
+}
+
+func TestReconcilesOnStartAndEventsFromCache(t *testing.T) {
+	test := startController(t, true, &v1.RadixRegistration{ObjectMeta: metav1.ObjectMeta{Name: "existing"}})
+	assertReconciled(t, []string{"existing"}, receive(t, test.reconciles, "the initial reconcile"))
+
+	test.createRr(t, "app")
+	assertReconciled(t, []string{"app", "existing"}, receive(t, test.reconciles, "a reconcile on the new RadixRegistration"))
+}
+
+func TestDoesNotReconcileOutsideWindow(t *testing.T) {
+	test := startController(t, false)
+	receive(t, test.checks, "the initial reconcile")
+
+	test.createRr(t, "app")
+	receive(t, test.checks, "a reconcile on the new RadixRegistration")
+	if len(test.reconciles) > 0 {
+		t.Errorf("expected no reconciles outside the window, got %v", <-test.reconciles)
+	}
+}
+
+func TestReconcilesOnResync(t *testing.T) {
+	test := startController(t, true)
+	start := test.clock.Now()
+	if checkedAt := receive(t, test.checks, "the initial reconcile"); !checkedAt.Equal(start) {
+		t.Errorf("expected the window to be checked at %s, got %s", start, checkedAt)
+	}
+	receive(t, test.reconciles, "the initial reconcile")
+
+	test.clock.Step(resync)
+	if checkedAt := receive(t, test.checks, "a reconcile on resync"); !checkedAt.Equal(start.Add(resync)) {
+		t.Errorf("expected the window to be checked at %s, got %s", start.Add(resync), checkedAt)
+	}
+	receive(t, test.reconciles, "a reconcile on resync")
+}
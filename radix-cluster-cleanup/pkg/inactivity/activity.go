@@ -0,0 +1,189 @@
+package inactivity
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	"github.com/equinor/radix-operator/pkg/apis/utils"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LastUserMutationAnnotation is set by Radix on RadixDeployments when a user changes them, e.g. by scaling a component
+const LastUserMutationAnnotation = "radix.equinor.com/last-user-mutation"
+
+// Actions RadixRegistrations are evaluated for
+const (
+	ActionStop     = "stop"
+	ActionDeletion = "deletion"
+)
+
+// Activity is the evidence the decision to stop or delete is based on
+type Activity struct {
+	AppName                    string       `json:"appName"`
+	Environment                string       `json:"environment,omitempty"`
+	Action                     string       `json:"action"`
+	RrCreated                  metav1.Time  `json:"rrCreated"`
+	LatestDeployment           string       `json:"latestDeployment,omitempty"`
+	LatestDeploymentActiveFrom *metav1.Time `json:"latestDeploymentActiveFrom,omitempty"`
+	LatestJob                  string       `json:"latestJob,omitempty"`
+	LatestJobCreated           *metav1.Time `json:"latestJobCreated,omitempty"`
+	LastUserMutation           *metav1.Time `json:"lastUserMutation,omitempty"`
+	LastActivity               metav1.Time  `json:"lastActivity"`
+	DaysInactive               int          `json:"daysInactive"`
+	Threshold                  string       `json:"threshold,omitempty"`
+	ThresholdSource            string       `json:"thresholdSource,omitempty"`
+	Exemption                  string       `json:"exemption,omitempty"`
+	PolicyRule                 string       `json:"policyRule,omitempty"`
+}
+
+// InactiveRr is a RadixRegistration, or one of its environments, found too inactive, with the activity it was evaluated on
+type InactiveRr struct {
+	Rr       v1.RadixRegistration
+	Activity Activity
+	// InactivityLimit is the effective inactivity threshold of the RadixRegistration
+	InactivityLimit time.Duration
+	// PastLimit is set if the last activity is older than the inactivity limit, not only within the warning period
+	// before it
+	PastLimit bool
+	// WarnOnly is set by policy rules which only warn owners, and never stop or delete
+	WarnOnly bool
+}
+
+// Logger returns the logger from the context with the app name, and environment if set
+func (inactiveRr InactiveRr) Logger(ctx context.Context) zerolog.Logger {
+	logContext := log.Ctx(ctx).With().Str("appName", inactiveRr.Rr.Name)
+	if inactiveRr.Activity.Environment != "" {
+		logContext = logContext.Str("environment", inactiveRr.Activity.Environment)
+	}
+	return logContext.Logger()
+}
+
+// GetActivity returns the last activity among the creation of a RadixRegistration, its latest RadixDeployment, the
+// last user mutation of it, and its latest RadixJob, with the latest RadixDeployment and RadixJob if any
+func GetActivity(ctx context.Context, now time.Time, appName string, rrCreationTimestamp metav1.Time, rds []v1.RadixDeployment, rjs []v1.RadixJob, action string) (*Activity, *v1.RadixDeployment, *v1.RadixJob, error) {
+	logger := log.Ctx(ctx)
+	activity := &Activity{AppName: appName, Action: action, RrCreated: rrCreationTimestamp, LastActivity: rrCreationTimestamp}
+	activity.DaysInactive = daysSince(now, activity.LastActivity)
+	if len(rds) == 0 {
+		logger.Debug().Msgf("no RadixDeployments found, last activity is creation of RadixRegistration")
+		return activity, nil, nil, nil
+	}
+
+	latestRadixDeployment := SortDeploymentsByActiveFromTimestampAsc(rds)[len(rds)-1]
+	latestRadixDeploymentTimestamp := latestRadixDeployment.Status.ActiveFrom
+	activity.LatestDeployment = latestRadixDeployment.Name
+	activity.LatestDeploymentActiveFrom = &latestRadixDeploymentTimestamp
+	logger.Debug().Msgf("most recent radixDeployment is %s, active from %s, %d hours ago", latestRadixDeployment.Name, latestRadixDeploymentTimestamp.Format(time.RFC822), hoursSince(now, latestRadixDeploymentTimestamp))
+
+	latestRadixJobTimestamp := metav1.Time{Time: time.Unix(0, 0)}
+	latestRadixJob := getLatestRadixJob(rjs)
+	if latestRadixJob != nil {
+		latestRadixJobTimestamp = *latestRadixJob.Status.Created
+		activity.LatestJob = latestRadixJob.Name
+		activity.LatestJobCreated = &latestRadixJobTimestamp
+		logger.Debug().Msgf("most recent radixJob was %s, created %s, %d hours ago", latestRadixJob.Name, latestRadixJobTimestamp.Format(time.RFC822), hoursSince(now, latestRadixJobTimestamp))
+	}
+
+	latestUserMutationTimestamp, err := getLastUserMutationTimestamp(latestRadixDeployment)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if latestUserMutationTimestamp.Unix() > 0 {
+		activity.LastUserMutation = latestUserMutationTimestamp
+	}
+
+	logger.Debug().Msgf("most recent manual user activity was %s, %d hours ago", latestUserMutationTimestamp.Format(time.RFC822), hoursSince(now, *latestUserMutationTimestamp))
+	logger.Debug().Msgf("most recent creation of RR was %s, %d hours ago", rrCreationTimestamp, hoursSince(now, rrCreationTimestamp))
+	lastActivity := getMostRecentTimestamp(&latestRadixJobTimestamp, latestUserMutationTimestamp, &latestRadixDeploymentTimestamp, &rrCreationTimestamp)
+	activity.LastActivity = *lastActivity
+	activity.DaysInactive = daysSince(now, *lastActivity)
+	logger.Debug().Msgf("lastActivity was %s, %d hours ago", lastActivity, hoursSince(now, *lastActivity))
+	return activity, &latestRadixDeployment, latestRadixJob, nil
+}
+
+// TooLongInactivity returns true if the last activity is older than the age limit
+func TooLongInactivity(now time.Time, lastActivity metav1.Time, ageLimit time.Duration) bool {
+	return lastActivity.Unix() < now.Add(-ageLimit).Unix()
+}
+
+// RuntimeNamespaces returns the namespaces of the environments of an application
+func RuntimeNamespaces(ra *v1.RadixApplication) []string {
+	namespaces := make([]string, 0)
+	for _, env := range ra.Spec.Environments {
+		namespaces = append(namespaces, utils.GetEnvironmentNamespace(ra.Name, env.Name))
+	}
+	return namespaces
+}
+
+func daysSince(now time.Time, timestamp metav1.Time) int {
+	return int(now.Sub(timestamp.Time).Hours() / 24)
+}
+
+func hoursSince(now time.Time, timestamp metav1.Time) int {
+	return int(now.Sub(timestamp.Time).Hours())
+}
+
+func getLatestRadixJob(rjs []v1.RadixJob) *v1.RadixJob {
+	if len(rjs) > 0 {
+		return &SortJobsByTimestampAsc(rjs)[len(rjs)-1]
+	}
+	return nil
+}
+
+func getLastUserMutationTimestamp(radixDeployment v1.RadixDeployment) (*metav1.Time, error) {
+	latestUserMutationTimestamp := metav1.Time{Time: time.Unix(0, 0)}
+	latestUserMutation, ok := radixDeployment.Annotations[LastUserMutationAnnotation]
+	if ok {
+		timestamp, err := time.Parse(time.RFC3339, latestUserMutation)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s annotation on RadixDeployment %s: %w", LastUserMutationAnnotation, radixDeployment.Name, err)
+		}
+		latestUserMutationTimestamp = metav1.Time{
+			Time: timestamp,
+		}
+	}
+	return &latestUserMutationTimestamp, nil
+}
+
+func getMostRecentTimestamp(timestamps ...*metav1.Time) *metav1.Time {
+	highestTimestamp := &metav1.Time{Time: time.Unix(0, 0)}
+	for _, timestamp := range timestamps {
+		if timestamp.After(highestTimestamp.Time) {
+			highestTimestamp = timestamp
+		}
+	}
+	return highestTimestamp
+}
+
+func SortJobsByTimestampAsc(rjs []v1.RadixJob) []v1.RadixJob {
+	sort.Slice(rjs, func(i, j int) bool {
+		return isRJ1CreatedAfterRJ2(&rjs[i], &rjs[j])
+	})
+	return rjs
+}
+
+func isRJ1CreatedAfterRJ2(rj1 *v1.RadixJob, rj2 *v1.RadixJob) bool {
+	rj1Created := rj1.CreationTimestamp
+	rj2Created := rj2.CreationTimestamp
+	return rj1Created.Before(&rj2Created)
+}
+
+func SortDeploymentsByActiveFromTimestampAsc(rds []v1.RadixDeployment) []v1.RadixDeployment {
+	target := make([]v1.RadixDeployment, len(rds))
+	copy(target, rds)
+	sort.Slice(target, func(i, j int) bool {
+		return isRD1ActiveAfterRD2(&target[j], &target[i])
+	})
+	return target
+}
+
+func isRD1ActiveAfterRD2(rd1 *v1.RadixDeployment, rd2 *v1.RadixDeployment) bool {
+	rj1ActiveFrom := rd1.Status.ActiveFrom
+	rj2ActiveFrom := rd2.Status.ActiveFrom
+	return rj2ActiveFrom.Before(&rj1ActiveFrom)
+}
@@ -0,0 +1,16 @@
+package inactivity
+
+import "time"
+
+// Clock tells the current time, so evaluation can be done at a fixed point in time
+type Clock interface {
+	Now() time.Time
+}
+
+// RealClock is the system clock
+type RealClock struct{}
+
+// Now returns the current local time
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
@@ -0,0 +1,225 @@
+package inactivity
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/metrics"
+	"github.com/equinor/radix-cluster-cleanup/pkg/policy"
+	"github.com/equinor/radix-cluster-cleanup/pkg/whitelist"
+	"github.com/equinor/radix-common/utils/slice"
+	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	"github.com/equinor/radix-operator/pkg/apis/utils"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/errgroup"
+)
+
+// Evaluator finds RadixRegistrations, or their environments, which have been inactive for too long
+type Evaluator struct {
+	clock       Clock
+	whitelist   *whitelist.Whitelist
+	policy      *policy.Policy
+	concurrency int
+}
+
+// Verdict is the outcome of evaluating a RadixRegistration, or one of its environments, for an action
+type Verdict struct {
+	InactiveRr
+	LatestRd *v1.RadixDeployment
+	LatestRj *v1.RadixJob
+	// Skipped is why the RadixRegistration or environment was not evaluated, e.g. it has no RadixApplication
+	Skipped string
+	// Exempt is set if the whitelist, the cleanup-exempt label or annotation, or a policy rule exempts it from the
+	// action. Activity.Exemption says why.
+	Exempt bool
+	// TooInactive is set if the last activity is older than the inactivity limit, less the warning period, and it is
+	// not exempt
+	TooInactive bool
+}
+
+// Failure is a RadixRegistration or environment which failed to be evaluated
+type Failure struct {
+	AppName     string
+	Environment string
+	Err         error
+}
+
+// NewEvaluator returns an Evaluator evaluating activity at the time of the clock. RadixRegistrations are evaluated
+// concurrently, at most concurrency at a time.
+func NewEvaluator(clock Clock, rrWhitelist *whitelist.Whitelist, cleanupPolicy *policy.Policy, concurrency int) *Evaluator {
+	return &Evaluator{
+		clock:       clock,
+		whitelist:   rrWhitelist,
+		policy:      cleanupPolicy,
+		concurrency: max(concurrency, 1),
+	}
+}
+
+// Clock returns the clock the evaluator evaluates activity at the time of
+func (e *Evaluator) Clock() Clock {
+	return e.clock
+}
+
+// Exemption returns why a RadixRegistration is exempt from cleanup by the whitelist, label or annotation, and false if
+// it is not
+func (e *Evaluator) Exemption(rr *v1.RadixRegistration) (string, bool) {
+	return Exemption(rr, e.whitelist, e.clock.Now())
+}
+
+// TooInactive returns the RadixRegistrations in resources with no activity within their inactivity threshold, less
+// the warning period, and those which failed to be evaluated.
+// With perEnvironment, each environment is evaluated separately and returned as its own entry.
+// Applications are evaluated concurrently, but returned in the order they are listed.
+func (e *Evaluator) TooInactive(ctx context.Context, resources *Resources, threshold Threshold, perEnvironment bool) ([]InactiveRr, []Failure) {
+	rrs := resources.RadixRegistrations()
+	verdicts := make([][]Verdict, len(rrs))
+	failures := make([][]Failure, len(rrs))
+	var group errgroup.Group
+	group.SetLimit(e.concurrency)
+	for i := range rrs {
+		group.Go(func() error {
+			verdicts[i], failures[i] = e.Evaluate(ctx, resources, rrs[i], threshold, perEnvironment)
+			return nil
+		})
+	}
+	_ = group.Wait()
+
+	var inactiveRrs []InactiveRr
+	for _, verdictsForRr := range verdicts {
+		for _, verdict := range verdictsForRr {
+			if verdict.TooInactive {
+				inactiveRrs = append(inactiveRrs, verdict.InactiveRr)
+			}
+		}
+	}
+	return inactiveRrs, slices.Concat(failures...)
+}
+
+// Evaluate returns the verdict for a RadixRegistration, or each of its environments with perEnvironment, and the
+// environments which failed to be evaluated
+func (e *Evaluator) Evaluate(ctx context.Context, resources *Resources, rr *v1.RadixRegistration, threshold Threshold, perEnvironment bool) ([]Verdict, []Failure) {
+	logger := log.Ctx(ctx).With().Str("appName", rr.Name).Logger()
+	ctx = logger.WithContext(ctx)
+	action := threshold.Action
+
+	metrics.AddRrEvaluated(action)
+	if exemption, ok := e.Exemption(rr); ok {
+		logger.Debug().Msgf("RadixRegistration is exempt (%s), skipping", exemption)
+		metrics.AddRrWhitelisted(action)
+		verdict := Verdict{Exempt: true, InactiveRr: InactiveRr{Rr: *rr, Activity: Activity{AppName: rr.Name, Action: action, RrCreated: rr.CreationTimestamp, Exemption: exemption}}}
+		return []Verdict{verdict}, nil
+	}
+	ra := resources.RadixApplication(rr.Name)
+	if ra == nil {
+		logger.Debug().Msg("could not find RadixApplication, continuing...")
+		return []Verdict{{Skipped: "no RadixApplication found", InactiveRr: InactiveRr{Rr: *rr, Activity: Activity{AppName: rr.Name, Action: action, RrCreated: rr.CreationTimestamp}}}}, nil
+	}
+	rjsForRr := resources.RadixJobs(rr.Name)
+	logger.Debug().Msgf("RadixRegistration has %d RadixJobs", len(rjsForRr))
+
+	if perEnvironment {
+		return e.evaluateEnvironments(ctx, resources, rr, ra, rjsForRr, threshold)
+	}
+
+	namespaces := RuntimeNamespaces(ra)
+	logger.Debug().Msgf("found namespaces %s associated with RadixRegistration", strings.Join(namespaces, ", "))
+	rdsForRr := resources.RadixDeployments(namespaces...)
+	logger.Debug().Msgf("RadixRegistration has %d RadixDeployments", len(rdsForRr))
+
+	verdict, err := e.EvaluateActivity(ctx, threshold, rr, ra, rdsForRr, rjsForRr, "")
+	if err != nil {
+		return nil, []Failure{{AppName: rr.Name, Err: err}}
+	}
+	return []Verdict{*verdict}, nil
+}
+
+// evaluateEnvironments evaluates each environment of a RadixRegistration on its own RadixDeployments and the
+// RadixJobs targeting it
+func (e *Evaluator) evaluateEnvironments(ctx context.Context, resources *Resources, rr *v1.RadixRegistration, ra *v1.RadixApplication, rjsForRr []v1.RadixJob, threshold Threshold) ([]Verdict, []Failure) {
+	var verdicts []Verdict
+	var failures []Failure
+	for _, env := range ra.Spec.Environments {
+		logger := log.Ctx(ctx).With().Str("environment", env.Name).Logger()
+		ctx := logger.WithContext(ctx)
+
+		metrics.AddEnvironmentEvaluated(threshold.Action)
+		rdsForEnv := resources.RadixDeployments(utils.GetEnvironmentNamespace(rr.Name, env.Name))
+		if len(rdsForEnv) == 0 {
+			logger.Debug().Msg("no RadixDeployments found in environment, skipping")
+			verdicts = append(verdicts, Verdict{Skipped: "no RadixDeployments found in environment", InactiveRr: InactiveRr{Rr: *rr, Activity: Activity{AppName: rr.Name, Environment: env.Name, Action: threshold.Action, RrCreated: rr.CreationTimestamp}}})
+			continue
+		}
+		rjsForEnv := slice.FindAll(rjsForRr, func(rj v1.RadixJob) bool {
+			return slices.Contains(rj.Status.TargetEnvironments, env.Name)
+		})
+		logger.Debug().Msgf("environment has %d RadixDeployments and %d RadixJobs", len(rdsForEnv), len(rjsForEnv))
+
+		verdict, err := e.EvaluateActivity(ctx, threshold, rr, ra, rdsForEnv, rjsForEnv, env.Name)
+		if err != nil {
+			failures = append(failures, Failure{AppName: rr.Name, Environment: env.Name, Err: err})
+			continue
+		}
+		verdicts = append(verdicts, *verdict)
+	}
+	return verdicts, failures
+}
+
+// EvaluateActivity finds the last activity of a RadixRegistration, or one of its environments, and whether it is
+// older than the threshold, less the warning period. The first matching policy rule may exempt it, only warn about
+// it, make it due regardless of activity, or replace the threshold.
+func (e *Evaluator) EvaluateActivity(ctx context.Context, threshold Threshold, rr *v1.RadixRegistration, ra *v1.RadixApplication, rds []v1.RadixDeployment, rjs []v1.RadixJob, environment string) (*Verdict, error) {
+	logger := log.Ctx(ctx)
+	now := e.clock.Now()
+	action := threshold.Action
+	activity, latestRd, latestRj, err := GetActivity(ctx, now, rr.Name, rr.CreationTimestamp, rds, rjs, action)
+	if err != nil {
+		return nil, err
+	}
+	activity.Environment = environment
+	inactivityLimit, thresholdSource := threshold.ForRr(rr)
+	verdict := &Verdict{InactiveRr: InactiveRr{Rr: *rr, Activity: *activity}, LatestRd: latestRd, LatestRj: latestRj}
+
+	rule, err := e.policy.Evaluate(policy.Input{
+		RadixRegistration: rr,
+		RadixApplication:  ra,
+		RadixDeployment:   latestRd,
+		RadixJob:          latestRj,
+		Action:            action,
+		Environment:       environment,
+		DaysInactive:      activity.DaysInactive,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if rule != nil {
+		logger.Debug().Msgf("policy rule %s matches, action %s", rule.Name, rule.Action)
+		verdict.Activity.PolicyRule = rule.Name
+		thresholdSource = "policy rule " + rule.Name
+		switch {
+		case rule.Action == policy.ActionExempt, rule.Action == policy.ActionStop && action == ActionDeletion:
+			logger.Debug().Msgf("exempt from %s by policy rule %s, skipping", action, rule.Name)
+			metrics.AddRrWhitelisted(action)
+			verdict.Exempt = true
+			verdict.Activity.Exemption = "policy rule " + rule.Name
+			return verdict, nil
+		case rule.Action == policy.ActionStop, rule.Action == policy.ActionDelete:
+			inactivityLimit = 0
+		case rule.Action == policy.ActionThreshold:
+			if inactivityLimit, err = ParseThreshold(rule.Threshold); err != nil {
+				return nil, err
+			}
+		case rule.Action == policy.ActionWarn:
+			verdict.WarnOnly = true
+		}
+	}
+
+	verdict.InactivityLimit = inactivityLimit
+	verdict.Activity.Threshold, verdict.Activity.ThresholdSource = FormatThreshold(inactivityLimit), thresholdSource
+	verdict.PastLimit = TooLongInactivity(now, activity.LastActivity, inactivityLimit)
+	verdict.TooInactive = TooLongInactivity(now, activity.LastActivity, inactivityLimit-threshold.WarningPeriod)
+	if verdict.TooInactive {
+		logger.Debug().Msgf("last activity was %d hours ago, which is more than %d hours ago, marking for %s", hoursSince(now, activity.LastActivity), int((inactivityLimit - threshold.WarningPeriod).Hours()), action)
+	}
+	return verdict, nil
+}
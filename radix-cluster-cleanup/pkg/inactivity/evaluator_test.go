@@ -0,0 +1,97 @@
+package inactivity_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/inactivity"
+	"github.com/equinor/radix-cluster-cleanup/pkg/policy"
+	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const day = 24 * time.Hour
+
+var now = time.Date(2026, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+// devOnlyScenario is an application with only a dev environment, inactive for 5 days
+func devOnlyScenario(rules ...policy.Rule) scenario {
+	return scenario{
+		Now:           now,
+		Policy:        rules,
+		Registrations: []v1.RadixRegistration{{ObjectMeta: metav1.ObjectMeta{Name: "app", CreationTimestamp: metav1.NewTime(now.AddDate(-1, 0, 0))}}},
+		Applications: []v1.RadixApplication{{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "app-app"},
+			Spec:       v1.RadixApplicationSpec{Environments: []v1.Environment{{Name: "dev"}}},
+		}},
+		Deployments: []v1.RadixDeployment{{
+			ObjectMeta: metav1.ObjectMeta{Name: "dev-1", Namespace: "app-dev"},
+			Status:     v1.RadixDeployStatus{ActiveFrom: metav1.NewTime(now.Add(-5 * day))},
+		}},
+	}
+}
+
+func TestThresholdRuleAppliesToBothActionsUnlessGuarded(t *testing.T) {
+	stopThreshold := inactivity.NewThreshold(inactivity.ActionStop, 7*day, 0, 0)
+	deletionThreshold := inactivity.NewThreshold(inactivity.ActionDeletion, 28*day, 0, 0)
+	tests := []struct {
+		name           string
+		when           string
+		expectDeletion []string
+	}{
+		{name: "unguarded", when: `environments == ["dev"]`, expectDeletion: []string{"app"}},
+		{name: "guarded by action", when: `action == "stop" && environments == ["dev"]`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			evaluator, resources := newEvaluator(t, devOnlyScenario(policy.Rule{Name: "dev-only", When: test.when, Action: policy.ActionThreshold, Threshold: "3d"}))
+
+			stop, _ := evaluate(t, evaluator, resources, stopThreshold, true)
+			assertNames(t, "stop", []string{"app/dev"}, stop)
+			deletion, _ := evaluate(t, evaluator, resources, deletionThreshold, false)
+			assertNames(t, "delete", test.expectDeletion, deletion)
+		})
+	}
+}
+
+func TestStopRuleRequiresDaysInactive(t *testing.T) {
+	if _, err := policy.New(policy.Rule{Name: "stop-dev", When: `environments == ["dev"]`, Action: policy.ActionStop}); err == nil {
+		t.Error("expected an error for a stop rule not using daysInactive")
+	}
+	evaluator, resources := newEvaluator(t, devOnlyScenario(policy.Rule{Name: "stop-dev", When: `environments == ["dev"] && daysInactive >= 3`, Action: policy.ActionStop}))
+	stop, _ := evaluate(t, evaluator, resources, inactivity.NewThreshold(inactivity.ActionStop, 7*day, 0, 0), true)
+	assertNames(t, "stop", []string{"app/dev"}, stop)
+}
+
+func TestMissingFieldFailsEvaluation(t *testing.T) {
+	threshold := inactivity.NewThreshold(inactivity.ActionDeletion, 28*day, 0, 0)
+	tests := []struct {
+		name        string
+		when        string
+		expectError bool
+	}{
+		{name: "without has", when: `rr.spec.configurationItem != ""`, expectError: true},
+		{name: "with has", when: `has(rr.spec.configurationItem) && rr.spec.configurationItem != ""`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			evaluator, resources := newEvaluator(t, devOnlyScenario(policy.Rule{Name: "keep-ci", When: test.when, Action: policy.ActionExempt}))
+
+			_, failures := evaluator.TooInactive(context.Background(), resources, threshold, false)
+			if !test.expectError {
+				if len(failures) > 0 {
+					t.Errorf("expected no failures, got %v", failures[0].Err)
+				}
+				return
+			}
+			if len(failures) != 1 || failures[0].AppName != "app" {
+				t.Fatalf("expected app to fail to be evaluated, got %v", failures)
+			}
+			if !strings.Contains(failures[0].Err.Error(), "keep-ci") {
+				t.Errorf("expected the error to name the rule, got %v", failures[0].Err)
+			}
+		})
+	}
+}
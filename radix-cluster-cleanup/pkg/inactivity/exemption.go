@@ -0,0 +1,53 @@
+package inactivity
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/whitelist"
+	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	"github.com/rs/zerolog/log"
+)
+
+// Label and annotations on RadixRegistrations exempting them from cleanup
+const (
+	ExemptLabelAndAnnotation = "radix.equinor.com/cleanup-exempt"
+	ExemptUntilAnnotation    = "radix.equinor.com/cleanup-exempt-until"
+	ExemptReasonAnnotation   = "radix.equinor.com/cleanup-exempt-reason"
+)
+
+// Exemption returns why a RadixRegistration is exempt from cleanup, and false if it is not
+func Exemption(rr *v1.RadixRegistration, rrWhitelist *whitelist.Whitelist, now time.Time) (string, bool) {
+	if exemption, ok := rrWhitelist.Match(rr.Name, now); ok {
+		return exemption, true
+	}
+	return exemptionFromRr(rr, now)
+}
+
+// exemptionFromRr returns the exemption declared by the cleanup-exempt label or annotation on a RadixRegistration,
+// unless it has expired
+func exemptionFromRr(rr *v1.RadixRegistration, now time.Time) (string, bool) {
+	source := "label"
+	if rr.Labels[ExemptLabelAndAnnotation] != "true" {
+		if rr.Annotations[ExemptLabelAndAnnotation] != "true" {
+			return "", false
+		}
+		source = "annotation"
+	}
+	exemption := fmt.Sprintf("%s %s", source, ExemptLabelAndAnnotation)
+	if until, ok := rr.Annotations[ExemptUntilAnnotation]; ok {
+		expiry, err := whitelist.ParseExpiry(until)
+		if err != nil {
+			log.Warn().Str("appName", rr.Name).Err(err).Msgf("invalid %s annotation, ignoring expiry", ExemptUntilAnnotation)
+		} else if !now.Before(expiry) {
+			log.Debug().Str("appName", rr.Name).Msgf("exemption expired %s", expiry.Format(time.RFC3339))
+			return "", false
+		} else {
+			exemption = fmt.Sprintf("%s until %s", exemption, until)
+		}
+	}
+	if reason := rr.Annotations[ExemptReasonAnnotation]; reason != "" {
+		exemption = fmt.Sprintf("%s: %s", exemption, reason)
+	}
+	return exemption, true
+}
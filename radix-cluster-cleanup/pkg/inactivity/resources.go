@@ -0,0 +1,183 @@
+package inactivity
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"slices"
+	"strings"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/metrics"
+	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	"github.com/equinor/radix-operator/pkg/apis/utils"
+	radixclient "github.com/equinor/radix-operator/pkg/client/clientset/versioned"
+	"github.com/rs/zerolog/log"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/pager"
+)
+
+// Resources holds RadixRegistrations, and RadixApplications, RadixDeployments and RadixJobs indexed by namespace,
+// listed once per run so evaluating, stopping and deleting applications needs no further list calls
+type Resources struct {
+	rrs            []*v1.RadixRegistration
+	rasByNamespace map[string]*v1.RadixApplication
+	rdsByNamespace map[string][]v1.RadixDeployment
+	rjsByNamespace map[string][]v1.RadixJob
+}
+
+// ListFunc returns the Resources a run evaluates and acts on
+type ListFunc func(ctx context.Context) (*Resources, error)
+
+func newResources() *Resources {
+	return &Resources{
+		rasByNamespace: make(map[string]*v1.RadixApplication),
+		rdsByNamespace: make(map[string][]v1.RadixDeployment),
+		rjsByNamespace: make(map[string][]v1.RadixJob),
+	}
+}
+
+// ListResources lists the RadixRegistrations, and RadixApplications, RadixDeployments and RadixJobs in all namespaces
+// in pages
+func ListResources(ctx context.Context, radixClient radixclient.Interface) (*Resources, error) {
+	radixV1 := radixClient.RadixV1()
+	resources := newResources()
+	err := EachListItem(ctx, func(opts metav1.ListOptions) (runtime.Object, error) {
+		return radixV1.RadixRegistrations().List(ctx, opts)
+	}, func(rr *v1.RadixRegistration) {
+		resources.rrs = append(resources.rrs, rr)
+	})
+	if err != nil {
+		metrics.AddError(metrics.ErrorKindListRegistrations)
+		return nil, fmt.Errorf("failed to list RadixRegistrations: %w", err)
+	}
+	err = EachListItem(ctx, func(opts metav1.ListOptions) (runtime.Object, error) {
+		return radixV1.RadixApplications(metav1.NamespaceAll).List(ctx, opts)
+	}, func(ra *v1.RadixApplication) {
+		resources.rasByNamespace[ra.Namespace] = ra
+	})
+	if err != nil {
+		metrics.AddError(metrics.ErrorKindGetApplication)
+		return nil, fmt.Errorf("failed to list RadixApplications: %w", err)
+	}
+	err = EachListItem(ctx, func(opts metav1.ListOptions) (runtime.Object, error) {
+		return radixV1.RadixDeployments(metav1.NamespaceAll).List(ctx, opts)
+	}, func(rd *v1.RadixDeployment) {
+		resources.rdsByNamespace[rd.Namespace] = append(resources.rdsByNamespace[rd.Namespace], *rd)
+	})
+	if err != nil {
+		metrics.AddError(metrics.ErrorKindListDeployments)
+		return nil, fmt.Errorf("failed to list RadixDeployments: %w", err)
+	}
+	err = EachListItem(ctx, func(opts metav1.ListOptions) (runtime.Object, error) {
+		return radixV1.RadixJobs(metav1.NamespaceAll).List(ctx, opts)
+	}, func(rj *v1.RadixJob) {
+		resources.rjsByNamespace[rj.Namespace] = append(resources.rjsByNamespace[rj.Namespace], *rj)
+	})
+	if err != nil {
+		metrics.AddError(metrics.ErrorKindListJobs)
+		return nil, fmt.Errorf("failed to list RadixJobs: %w", err)
+	}
+	log.Ctx(ctx).Debug().Msgf("listed %d RadixRegistrations, %d RadixApplications, RadixDeployments in %d namespaces and RadixJobs in %d namespaces", len(resources.rrs), len(resources.rasByNamespace), len(resources.rdsByNamespace), len(resources.rjsByNamespace))
+	return resources, nil
+}
+
+// NewResources returns Resources holding the given objects, e.g. from informer caches. The objects are not copied, and
+// must not be modified.
+func NewResources(rrs []*v1.RadixRegistration, ras []*v1.RadixApplication, rds []*v1.RadixDeployment, rjs []*v1.RadixJob) *Resources {
+	resources := newResources()
+	resources.rrs = slices.SortedFunc(slices.Values(rrs), func(a, b *v1.RadixRegistration) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	for _, ra := range ras {
+		resources.rasByNamespace[ra.Namespace] = ra
+	}
+	for _, rd := range rds {
+		resources.rdsByNamespace[rd.Namespace] = append(resources.rdsByNamespace[rd.Namespace], *rd)
+	}
+	for _, rj := range rjs {
+		resources.rjsByNamespace[rj.Namespace] = append(resources.rjsByNamespace[rj.Namespace], *rj)
+	}
+	return resources
+}
+
+// ListAppResources lists the RadixApplication, RadixDeployments and RadixJobs of a single application, for evaluating
+// one application without listing the whole cluster. The RadixRegistration is not included.
+func ListAppResources(ctx context.Context, radixClient radixclient.Interface, appName string) (*Resources, error) {
+	resources := newResources()
+	appNamespace := utils.GetAppNamespace(appName)
+	ra, err := radixClient.RadixV1().RadixApplications(appNamespace).Get(ctx, appName, metav1.GetOptions{})
+	if kubeerrors.IsNotFound(err) {
+		return resources, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get RadixApplication: %w", err)
+	}
+	resources.rasByNamespace[appNamespace] = ra
+	for _, namespace := range RuntimeNamespaces(ra) {
+		rds, err := radixClient.RadixV1().RadixDeployments(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list RadixDeployments in %s: %w", namespace, err)
+		}
+		resources.rdsByNamespace[namespace] = rds.Items
+	}
+	rjs, err := radixClient.RadixV1().RadixJobs(appNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list RadixJobs: %w", err)
+	}
+	resources.rjsByNamespace[appNamespace] = rjs.Items
+	return resources, nil
+}
+
+// EachListItem lists all items of a resource in pages, falling back to a full list if the continue token expires
+func EachListItem[T any, PT interface {
+	*T
+	runtime.Object
+}](ctx context.Context, list func(opts metav1.ListOptions) (runtime.Object, error), fn func(item PT)) error {
+	return pager.New(pager.SimplePageFunc(list)).EachListItemWithAlloc(ctx, metav1.ListOptions{}, func(obj runtime.Object) error {
+		item, ok := obj.(PT)
+		if !ok {
+			return fmt.Errorf("unexpected list item type %T", obj)
+		}
+		fn(item)
+		return nil
+	})
+}
+
+// RadixRegistrations returns the RadixRegistrations in the order they were listed
+func (r *Resources) RadixRegistrations() []*v1.RadixRegistration {
+	return r.rrs
+}
+
+// AllRadixDeployments returns the RadixDeployments in all namespaces
+func (r *Resources) AllRadixDeployments() iter.Seq[*v1.RadixDeployment] {
+	return func(yield func(*v1.RadixDeployment) bool) {
+		for _, rds := range r.rdsByNamespace {
+			for i := range rds {
+				if !yield(&rds[i]) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// RadixApplication returns the RadixApplication of an application, or nil if it has none
+func (r *Resources) RadixApplication(appName string) *v1.RadixApplication {
+	return r.rasByNamespace[utils.GetAppNamespace(appName)]
+}
+
+// RadixDeployments returns the RadixDeployments in the given namespaces
+func (r *Resources) RadixDeployments(namespaces ...string) []v1.RadixDeployment {
+	rds := make([]v1.RadixDeployment, 0)
+	for _, namespace := range namespaces {
+		rds = append(rds, r.rdsByNamespace[namespace]...)
+	}
+	return rds
+}
+
+// RadixJobs returns the RadixJobs of an application
+func (r *Resources) RadixJobs(appName string) []v1.RadixJob {
+	return r.rjsByNamespace[utils.GetAppNamespace(appName)]
+}
@@ -0,0 +1,162 @@
+package inactivity_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/inactivity"
+	"github.com/equinor/radix-cluster-cleanup/pkg/policy"
+	"github.com/equinor/radix-cluster-cleanup/pkg/whitelist"
+	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	"github.com/equinor/radix-operator/pkg/client/clientset/versioned/fake"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+// scenario is a fixture of a cluster at a point in time, and the environments and applications expected to be stopped,
+// deleted or warned about
+type scenario struct {
+	Description   string                 `json:"description"`
+	Now           time.Time              `json:"now"`
+	Thresholds    thresholds             `json:"thresholds"`
+	Whitelist     []whitelist.Entry      `json:"whitelist"`
+	Policy        []policy.Rule          `json:"policy"`
+	Registrations []v1.RadixRegistration `json:"registrations"`
+	Applications  []v1.RadixApplication  `json:"applications"`
+	Deployments   []v1.RadixDeployment   `json:"deployments"`
+	Jobs          []v1.RadixJob          `json:"jobs"`
+	Expect        expectations           `json:"expect"`
+}
+
+type thresholds struct {
+	StopDays         int `json:"stopDays"`
+	DeletionDays     int `json:"deletionDays"`
+	MaxStopDays      int `json:"maxStopDays"`
+	MaxDeletionDays  int `json:"maxDeletionDays"`
+	WarnStopDays     int `json:"warnStopDays"`
+	WarnDeletionDays int `json:"warnDeletionDays"`
+}
+
+// expectations lists environments as app/env for stop, and applications by name for deletion
+type expectations struct {
+	Stop       []string `json:"stop"`
+	WarnStop   []string `json:"warnStop"`
+	Delete     []string `json:"delete"`
+	WarnDelete []string `json:"warnDelete"`
+}
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+func TestScenarios(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "scenarios", "*.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no scenarios found in testdata/scenarios")
+	}
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			s := loadScenario(t, path)
+			t.Log(s.Description)
+			evaluator, resources := newEvaluator(t, s)
+			day := 24 * time.Hour
+
+			stopThreshold := inactivity.NewThreshold(inactivity.ActionStop, time.Duration(s.Thresholds.StopDays)*day, time.Duration(s.Thresholds.MaxStopDays)*day, time.Duration(s.Thresholds.WarnStopDays)*day)
+			stop, warnStop := evaluate(t, evaluator, resources, stopThreshold, true)
+			assertNames(t, "stop", s.Expect.Stop, stop)
+			assertNames(t, "warnStop", s.Expect.WarnStop, warnStop)
+
+			deletionThreshold := inactivity.NewThreshold(inactivity.ActionDeletion, time.Duration(s.Thresholds.DeletionDays)*day, time.Duration(s.Thresholds.MaxDeletionDays)*day, time.Duration(s.Thresholds.WarnDeletionDays)*day)
+			deletion, warnDeletion := evaluate(t, evaluator, resources, deletionThreshold, false)
+			assertNames(t, "delete", s.Expect.Delete, deletion)
+			assertNames(t, "warnDelete", s.Expect.WarnDelete, warnDeletion)
+		})
+	}
+}
+
+func loadScenario(t *testing.T, path string) scenario {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s scenario
+	if err := yaml.UnmarshalStrict(data, &s); err != nil {
+		t.Fatalf("failed to parse %s: %v", path, err)
+	}
+	return s
+}
+
+// newEvaluator returns an evaluator configured by the scenario, and its resources listed from a fake radix client
+func newEvaluator(t *testing.T, s scenario) (*inactivity.Evaluator, *inactivity.Resources) {
+	t.Helper()
+	for i := range s.Whitelist {
+		s.Whitelist[i].Source = "scenario"
+	}
+	rrWhitelist, err := whitelist.New(s.Whitelist...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanupPolicy, err := policy.New(s.Policy...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var objects []runtime.Object
+	for i := range s.Registrations {
+		objects = append(objects, &s.Registrations[i])
+	}
+	for i := range s.Applications {
+		objects = append(objects, &s.Applications[i])
+	}
+	for i := range s.Deployments {
+		objects = append(objects, &s.Deployments[i])
+	}
+	for i := range s.Jobs {
+		objects = append(objects, &s.Jobs[i])
+	}
+	resources, err := inactivity.ListResources(context.Background(), fake.NewSimpleClientset(objects...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return inactivity.NewEvaluator(fixedClock(s.Now), rrWhitelist, cleanupPolicy, 2), resources
+}
+
+// evaluate returns the names of the applications, or environments as app/env, due for the action, and those only
+// warned about
+func evaluate(t *testing.T, evaluator *inactivity.Evaluator, resources *inactivity.Resources, threshold inactivity.Threshold, perEnvironment bool) ([]string, []string) {
+	t.Helper()
+	inactiveRrs, failures := evaluator.TooInactive(context.Background(), resources, threshold, perEnvironment)
+	for _, failure := range failures {
+		t.Errorf("failed to evaluate %s %s: %v", failure.AppName, failure.Environment, failure.Err)
+	}
+	var due, warned []string
+	for _, inactiveRr := range inactiveRrs {
+		name := inactiveRr.Activity.AppName
+		if perEnvironment {
+			name += "/" + inactiveRr.Activity.Environment
+		}
+		if inactiveRr.PastLimit && !inactiveRr.WarnOnly {
+			due = append(due, name)
+		} else {
+			warned = append(warned, name)
+		}
+	}
+	return due, warned
+}
+
+func assertNames(t *testing.T, outcome string, expected, actual []string) {
+	t.Helper()
+	expected, actual = slices.Sorted(slices.Values(expected)), slices.Sorted(slices.Values(actual))
+	if !slices.Equal(expected, actual) {
+		t.Errorf("%s: expected %v, got %v", outcome, expected, actual)
+	}
+}
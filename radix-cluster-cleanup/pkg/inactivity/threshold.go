@@ -0,0 +1,100 @@
+package inactivity
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	"github.com/rs/zerolog/log"
+)
+
+// Annotations on RadixRegistrations overriding the inactivity thresholds
+const (
+	StopAfterAnnotation   = "radix.equinor.com/cleanup-stop-after"
+	DeleteAfterAnnotation = "radix.equinor.com/cleanup-delete-after"
+)
+
+// Where the inactivity limit of a RadixRegistration came from
+const (
+	ThresholdSourceDefault    = "default"
+	ThresholdSourceAnnotation = "annotation"
+	ThresholdSourceCapped     = "annotation, capped"
+)
+
+// Threshold is how long RadixRegistrations may be inactive before an action, and how far an annotation on a
+// RadixRegistration may extend it
+type Threshold struct {
+	Action     string
+	Annotation string
+	Limit      time.Duration
+	Max        time.Duration
+	// WarningPeriod is how long before the limit owners are warned, so RadixRegistrations are evaluated this much earlier
+	WarningPeriod time.Duration
+}
+
+// NewThreshold returns the threshold for the stop or deletion action. A max below the limit means annotations can
+// only shorten the limit.
+func NewThreshold(action string, limit, max, warningPeriod time.Duration) Threshold {
+	annotation := StopAfterAnnotation
+	if action == ActionDeletion {
+		annotation = DeleteAfterAnnotation
+	}
+	if max < limit {
+		max = limit
+	}
+	return Threshold{Action: action, Annotation: annotation, Limit: limit, Max: max, WarningPeriod: warningPeriod}
+}
+
+// ForRr returns the inactivity limit of a RadixRegistration, and where it came from. The annotation may shorten the
+// limit, or extend it up to the max. An invalid annotation is ignored.
+func (threshold Threshold) ForRr(rr *v1.RadixRegistration) (time.Duration, string) {
+	value, ok := rr.Annotations[threshold.Annotation]
+	if !ok {
+		return threshold.Limit, ThresholdSourceDefault
+	}
+	limit, err := ParseThreshold(value)
+	if err != nil {
+		log.Warn().Str("appName", rr.Name).Err(err).Msgf("invalid %s annotation, using default threshold", threshold.Annotation)
+		return threshold.Limit, ThresholdSourceDefault
+	}
+	if limit > threshold.Max {
+		log.Debug().Str("appName", rr.Name).Msgf("%s annotation %s exceeds max %s, capping", threshold.Annotation, value, FormatThreshold(threshold.Max))
+		return threshold.Max, ThresholdSourceCapped
+	}
+	return limit, ThresholdSourceAnnotation
+}
+
+// ParseThreshold parses a number of days (30d), weeks (4w) or a Go duration (36h)
+func ParseThreshold(value string) (time.Duration, error) {
+	var threshold time.Duration
+	var err error
+	switch {
+	case strings.HasSuffix(value, "d"), strings.HasSuffix(value, "w"):
+		var count int64
+		count, err = strconv.ParseInt(value[:len(value)-1], 10, 64)
+		threshold = time.Hour * 24 * time.Duration(count)
+		if strings.HasSuffix(value, "w") {
+			threshold *= 7
+		}
+	default:
+		threshold, err = time.ParseDuration(value)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid threshold %q, expected days (30d), weeks (4w) or a duration (36h)", value)
+	}
+	if threshold <= 0 {
+		return 0, fmt.Errorf("invalid threshold %q, must be positive", value)
+	}
+	return threshold, nil
+}
+
+// FormatThreshold formats whole days as 30d, and other durations as Go durations
+func FormatThreshold(threshold time.Duration) string {
+	day := time.Hour * 24
+	if threshold%day == 0 {
+		return fmt.Sprintf("%dd", threshold/day)
+	}
+	return threshold.String()
+}
@@ -0,0 +1,42 @@
+package inactivity
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseThreshold(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{value: "30d", expected: 30 * 24 * time.Hour},
+		{value: "4w", expected: 28 * 24 * time.Hour},
+		{value: "36h", expected: 36 * time.Hour},
+		{value: "0d", wantErr: true},
+		{value: "-1w", wantErr: true},
+		{value: "d", wantErr: true},
+		{value: "soon", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			threshold, err := ParseThreshold(test.value)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %s", threshold)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if threshold != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, threshold)
+			}
+			if formatted, _ := ParseThreshold(FormatThreshold(threshold)); formatted != threshold {
+				t.Errorf("%s does not round trip through FormatThreshold", test.value)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/log"
+)
+
+const namespace = "radix_cluster_cleanup"
+
+// Error kinds used as the kind label on the errors counter
+const (
+	ErrorKindListRegistrations = "list_registrations"
+	ErrorKindGetApplication    = "get_application"
+	ErrorKindListDeployments   = "list_deployments"
+	ErrorKindListJobs          = "list_jobs"
+	ErrorKindEvaluate          = "evaluate"
+	ErrorKindStop              = "stop"
+	ErrorKindUnmark            = "unmark"
+	ErrorKindDelete            = "delete"
+	ErrorKindNotify            = "notify"
+	ErrorKindRun               = "run"
+	ErrorKindCircuitBreaker    = "circuit_breaker"
+	ErrorKindBackup            = "backup"
+)
+
+var (
+	rrsEvaluated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rrs_evaluated_total",
+		Help:      "Number of RadixRegistrations evaluated for inactivity",
+	}, []string{"action"})
+	rrsWhitelisted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rrs_whitelisted_total",
+		Help:      "Number of RadixRegistrations skipped because they are whitelisted",
+	}, []string{"action"})
+	rrsMarkedLastRun = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "rrs_marked",
+		Help:      "Number of RadixRegistrations marked for stop or deletion in the last run",
+	}, []string{"action"})
+	environmentsEvaluated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "environments_evaluated_total",
+		Help:      "Number of application environments evaluated for inactivity",
+	}, []string{"action"})
+	environmentsMarkedLastRun = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "environments_marked",
+		Help:      "Number of application environments marked for stop in the last run",
+	}, []string{"action"})
+	environmentsStopped = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "environments_stopped_total",
+		Help:      "Number of application environments stopped",
+	})
+	rrsWarned = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rrs_warned_total",
+		Help:      "Number of RadixRegistrations whose owners were warned about an upcoming stop or deletion",
+	}, []string{"action"})
+	componentsStopped = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "components_stopped_total",
+		Help:      "Number of components scaled to zero replicas",
+	})
+	jobComponentsStopped = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "job_components_stopped_total",
+		Help:      "Number of job components with outstanding batches stopped, and job scheduler scaled to zero replicas until the operator next reconciles it",
+	})
+	rrsDeleted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rrs_deleted_total",
+		Help:      "Number of RadixRegistrations deleted",
+	})
+	circuitBreakerTripped = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "circuit_breaker_tripped",
+		Help:      "1 if the circuit breaker is tripped and refuses stops and deletions until acknowledged, otherwise 0",
+	})
+	runDuration = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "run_duration_seconds",
+		Help:      "Duration of the last cleanup run",
+	})
+	lastSuccessfulRun = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_successful_run_timestamp_seconds",
+		Help:      "Unix timestamp of the last cleanup run that completed without errors",
+	})
+	errorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "errors_total",
+		Help:      "Number of errors by kind",
+	}, []string{"kind"})
+)
+
+// AddRrEvaluated increments the number of evaluated RadixRegistrations for an action
+func AddRrEvaluated(action string) {
+	rrsEvaluated.WithLabelValues(action).Inc()
+}
+
+// AddRrWhitelisted increments the number of whitelisted RadixRegistrations for an action
+func AddRrWhitelisted(action string) {
+	rrsWhitelisted.WithLabelValues(action).Inc()
+}
+
+// SetRrsMarked records the number of RadixRegistrations due for an action in a run. It is a gauge only, as the same
+// RadixRegistrations are due in every run until they are acted on.
+func SetRrsMarked(action string, count int) {
+	rrsMarkedLastRun.WithLabelValues(action).Set(float64(count))
+}
+
+// AddEnvironmentEvaluated increments the number of evaluated application environments for an action
+func AddEnvironmentEvaluated(action string) {
+	environmentsEvaluated.WithLabelValues(action).Inc()
+}
+
+// SetEnvironmentsMarked records the number of application environments due for an action in a run. It is a gauge only,
+// as the same environments are due in every run until they are stopped.
+func SetEnvironmentsMarked(action string, count int) {
+	environmentsMarkedLastRun.WithLabelValues(action).Set(float64(count))
+}
+
+// AddEnvironmentStopped increments the number of stopped application environments
+func AddEnvironmentStopped() {
+	environmentsStopped.Inc()
+}
+
+// AddRrWarned increments the number of RadixRegistrations whose owners were warned about an action
+func AddRrWarned(action string) {
+	rrsWarned.WithLabelValues(action).Inc()
+}
+
+// AddComponentsStopped increments the number of components scaled to zero replicas
+func AddComponentsStopped(count int) {
+	componentsStopped.Add(float64(count))
+}
+
+// AddJobComponentsStopped increments the number of stopped job components
+func AddJobComponentsStopped(count int) {
+	jobComponentsStopped.Add(float64(count))
+}
+
+// AddRrDeleted increments the number of deleted RadixRegistrations
+func AddRrDeleted() {
+	rrsDeleted.Inc()
+}
+
+// SetCircuitBreakerTripped records whether the circuit breaker is tripped
+func SetCircuitBreakerTripped(tripped bool) {
+	if tripped {
+		circuitBreakerTripped.Set(1)
+		return
+	}
+	circuitBreakerTripped.Set(0)
+}
+
+// AddError increments the error counter for an error kind
+func AddError(kind string) {
+	errorsTotal.WithLabelValues(kind).Inc()
+}
+
+// ObserveRun records the duration of a run, and the time it completed if it succeeded
+func ObserveRun(start time.Time, err error) {
+	runDuration.Set(time.Since(start).Seconds())
+	if err != nil {
+		AddError(ErrorKindRun)
+		return
+	}
+	lastSuccessfulRun.SetToCurrentTime()
+}
+
+// Serve exposes the /metrics endpoint on the given port until the context is cancelled
+func Serve(ctx context.Context, port int) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%d", port),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Ctx(ctx).Warn().Err(err).Msg("failed to shut down metrics server")
+		}
+	}()
+	log.Ctx(ctx).Info().Msgf("Serving metrics on port %d", port)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Notification describes an upcoming stop or deletion of an application
+type Notification struct {
+	AppName           string    `json:"appName"`
+	Environment       string    `json:"environment,omitempty"`
+	Action            string    `json:"action"`
+	Owner             string    `json:"owner,omitempty"`
+	AdGroups          []string  `json:"adGroups,omitempty"`
+	ConfigurationItem string    `json:"configurationItem,omitempty"`
+	LastActivity      time.Time `json:"lastActivity"`
+	DaysInactive      int       `json:"daysInactive"`
+	ActionAt          time.Time `json:"actionAt"`
+}
+
+// Notifier sends warnings to the owners of an application
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// Subject returns a one-line summary of the notification
+func (n Notification) Subject() string {
+	if n.Environment != "" {
+		return fmt.Sprintf("Environment %s in Radix application %s is scheduled for %s", n.Environment, n.AppName, n.Action)
+	}
+	return fmt.Sprintf("Radix application %s is scheduled for %s", n.AppName, n.Action)
+}
+
+// Message returns the human-readable body of the notification
+func (n Notification) Message() string {
+	var b strings.Builder
+	if n.Environment != "" {
+		_, _ = fmt.Fprintf(&b, "The environment %s in Radix application %s has been inactive for %d days, since %s.\n", n.Environment, n.AppName, n.DaysInactive, n.LastActivity.UTC().Format(time.RFC1123))
+	} else {
+		_, _ = fmt.Fprintf(&b, "The Radix application %s has been inactive for %d days, since %s.\n", n.AppName, n.DaysInactive, n.LastActivity.UTC().Format(time.RFC1123))
+	}
+	_, _ = fmt.Fprintf(&b, "It will be scheduled for %s after %s unless there is new activity, such as a new deployment or pipeline job.\n", n.Action, n.ActionAt.UTC().Format(time.RFC1123))
+	if n.ConfigurationItem != "" {
+		_, _ = fmt.Fprintf(&b, "Configuration item: %s\n", n.ConfigurationItem)
+	}
+	return b.String()
+}
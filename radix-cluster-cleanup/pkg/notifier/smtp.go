@@ -0,0 +1,126 @@
+package notifier
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// smtpTimeout is the longest an email may take to send, like the timeout of webhook requests
+const smtpTimeout = 30 * time.Second
+
+type smtpNotifier struct {
+	host     string
+	port     int
+	from     string
+	username string
+	password string
+}
+
+// NewSmtpNotifier returns a Notifier which emails the owner of the application
+func NewSmtpNotifier(host string, port int, from, username, password string) Notifier {
+	return &smtpNotifier{host: host, port: port, from: from, username: username, password: password}
+}
+
+func (n *smtpNotifier) Notify(ctx context.Context, notification Notification) error {
+	if notification.Owner == "" {
+		return errors.New("application has no owner to email")
+	}
+	if strings.ContainsAny(notification.Owner, "\r\n") {
+		return fmt.Errorf("invalid owner email address %q", notification.Owner)
+	}
+	var auth smtp.Auth
+	if n.username != "" {
+		auth = smtp.PlainAuth("", n.username, n.password, n.host)
+	}
+	message := strings.Join([]string{
+		fmt.Sprintf("From: %s", n.from),
+		fmt.Sprintf("To: %s", notification.Owner),
+		fmt.Sprintf("Subject: %s", notification.Subject()),
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		notification.Message(),
+	}, "\r\n")
+	return n.sendMail(ctx, auth, notification.Owner, []byte(message))
+}
+
+// sendMail sends a message like smtp.SendMail, within the deadline of the context, or smtpTimeout if earlier, and
+// aborts when the context is cancelled, so an unresponsive server does not block the run
+func (n *smtpNotifier) sendMail(ctx context.Context, auth smtp.Auth, to string, message []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, smtpTimeout)
+	defer cancel()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(n.host, strconv.Itoa(n.port)))
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		_ = conn.Close()
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+	client, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		_ = conn.Close()
+		return withContextErr(ctx, err)
+	}
+	defer func() { _ = client.Close() }()
+	return withContextErr(ctx, n.converse(client, auth, to, message))
+}
+
+// converse runs the SMTP conversation of smtp.SendMail on a connected client
+func (n *smtpNotifier) converse(client *smtp.Client, auth smtp.Auth, to string, message []byte) error {
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: n.host}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("smtp server does not support AUTH")
+		}
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(n.from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(message); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// withContextErr adds the context error to an error caused by the connection being closed on cancellation, or passing
+// its deadline, which is the deadline of the context
+func withContextErr(ctx context.Context, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case ctx.Err() != nil:
+		return errors.Join(ctx.Err(), err)
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		return errors.Join(context.DeadlineExceeded, err)
+	}
+	return err
+}
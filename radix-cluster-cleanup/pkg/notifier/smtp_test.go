@@ -0,0 +1,106 @@
+package notifier_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/notifier"
+)
+
+var notification = notifier.Notification{AppName: "app", Action: "stop", Owner: "owner@example.com"}
+
+// listen returns a listener on a local port, and the notifier sending to it
+func listen(t *testing.T) (net.Listener, notifier.Notifier) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	port := listener.Addr().(*net.TCPAddr).Port
+	return listener, notifier.NewSmtpNotifier("127.0.0.1", port, "cleanup@example.com", "", "")
+}
+
+// serveSmtp answers one SMTP conversation, and returns the message received
+func serveSmtp(listener net.Listener) <-chan string {
+	messages := make(chan string, 1)
+	go func() {
+		defer close(messages)
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		text := textproto.NewConn(conn)
+		_ = text.PrintfLine("220 test")
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			switch command, _, _ := strings.Cut(line, " "); command {
+			case "EHLO":
+				_ = text.PrintfLine("250 test")
+			case "DATA":
+				_ = text.PrintfLine("354 go ahead")
+				message, err := text.ReadDotLines()
+				if err != nil {
+					return
+				}
+				messages <- strings.Join(message, "\n")
+				_ = text.PrintfLine("250 ok")
+			case "QUIT":
+				_ = text.PrintfLine("221 bye")
+				return
+			default:
+				_ = text.PrintfLine("250 ok")
+			}
+		}
+	}()
+	return messages
+}
+
+func TestSmtpNotify(t *testing.T) {
+	listener, smtpNotifier := listen(t)
+	messages := serveSmtp(listener)
+	if err := smtpNotifier.Notify(context.Background(), notification); err != nil {
+		t.Fatal(err)
+	}
+	if message := <-messages; !strings.Contains(message, "To: owner@example.com") || !strings.Contains(message, notification.Subject()) {
+		t.Errorf("expected a message to the owner with the subject, got %q", message)
+	}
+}
+
+func TestSmtpNotifyStopsOnContextDeadline(t *testing.T) {
+	listener, smtpNotifier := listen(t)
+	// The server accepts connections, but never answers
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		_, _ = bufio.NewReader(conn).ReadString(0)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- smtpNotifier.Notify(ctx, notification)
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected the context deadline to be exceeded, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected Notify to return when the context deadline is exceeded")
+	}
+}
@@ -0,0 +1,53 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type webhookNotifier struct {
+	url     string
+	client  *http.Client
+	payload func(Notification) any
+}
+
+type chatMessage struct {
+	Text string `json:"text"`
+}
+
+// NewWebhookNotifier returns a Notifier which posts the notification as JSON to a URL
+func NewWebhookNotifier(url string) Notifier {
+	return &webhookNotifier{url: url, client: &http.Client{Timeout: 30 * time.Second}, payload: func(n Notification) any { return n }}
+}
+
+// NewIncomingWebhookNotifier returns a Notifier which posts a text message to a Slack or Teams incoming webhook
+func NewIncomingWebhookNotifier(url string) Notifier {
+	return &webhookNotifier{url: url, client: &http.Client{Timeout: 30 * time.Second}, payload: func(n Notification) any {
+		return chatMessage{Text: fmt.Sprintf("*%s*\n%s", n.Subject(), n.Message())}
+	}}
+}
+
+func (n *webhookNotifier) Notify(ctx context.Context, notification Notification) error {
+	body, err := json.Marshal(n.payload(notification))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %s", resp.Status)
+	}
+	return nil
+}
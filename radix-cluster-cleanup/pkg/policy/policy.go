@@ -0,0 +1,237 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+
+	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	"github.com/google/cel-go/cel"
+	"sigs.k8s.io/yaml"
+)
+
+// Action is what a rule does with the RadixRegistrations or environments it matches
+type Action string
+
+const (
+	// ActionExempt exempts from the stop or deletion being evaluated
+	ActionExempt Action = "exempt"
+	// ActionWarn warns the owners when the threshold is reached, but never stops or deletes
+	ActionWarn Action = "warn"
+	// ActionStop stops regardless of the threshold, and exempts from deletion. The expression must use daysInactive, so
+	// the rule states how inactive an application must be.
+	ActionStop Action = "stop"
+	// ActionDelete stops and deletes regardless of the threshold. The expression must use daysInactive, like ActionStop.
+	ActionDelete Action = "delete"
+	// ActionThreshold replaces the threshold of the action being evaluated with the threshold of the rule. It applies to
+	// both stop and deletion, unless the expression checks action.
+	ActionThreshold Action = "threshold"
+)
+
+// daysInactiveVariable must be used by the expressions of stop and delete rules
+const daysInactiveVariable = "daysInactive"
+
+// Rule maps RadixRegistrations or environments matching a CEL expression to an action
+type Rule struct {
+	Name string `json:"name"`
+	// When is a CEL expression returning a bool, see Input for the variables
+	When      string `json:"when"`
+	Action    Action `json:"action"`
+	Threshold string `json:"threshold,omitempty"`
+	Source    string `json:"-"`
+
+	program cel.Program
+}
+
+// Policy is an ordered list of rules, the first matching rule applies
+type Policy struct {
+	rules []Rule
+}
+
+// Input is what rules are evaluated on. RadixRegistration, RadixApplication, latest RadixDeployment and latest
+// RadixJob are available to expressions as rr, ra, rd and rj, in their JSON form, with rd and rj empty if there are
+// none. Selecting a key missing from them is an error, failing the evaluation, so optional fields must be checked with
+// has(), e.g. has(rr.spec.configurationItem). The other fields are available as action ("stop" or "deletion"), appName, environment (empty when evaluating
+// the application as a whole), environments, envCount, owner and daysInactive.
+type Input struct {
+	RadixRegistration *v1.RadixRegistration
+	RadixApplication  *v1.RadixApplication
+	RadixDeployment   *v1.RadixDeployment
+	RadixJob          *v1.RadixJob
+	Action            string
+	Environment       string
+	DaysInactive      int
+}
+
+var env *cel.Env
+
+func init() {
+	var err error
+	env, err = cel.NewEnv(
+		cel.Variable("rr", cel.DynType),
+		cel.Variable("ra", cel.DynType),
+		cel.Variable("rd", cel.DynType),
+		cel.Variable("rj", cel.DynType),
+		cel.Variable("action", cel.StringType),
+		cel.Variable("appName", cel.StringType),
+		cel.Variable("environment", cel.StringType),
+		cel.Variable("environments", cel.ListType(cel.StringType)),
+		cel.Variable("envCount", cel.IntType),
+		cel.Variable("owner", cel.StringType),
+		cel.Variable("daysInactive", cel.IntType),
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// New returns a Policy with the given rules, compiling their expressions
+func New(rules ...Rule) (*Policy, error) {
+	p := &Policy{}
+	for _, rule := range rules {
+		if err := rule.compile(); err != nil {
+			return nil, err
+		}
+		p.rules = append(p.rules, rule)
+	}
+	return p, nil
+}
+
+// Parse reads a YAML list of rules, tagging each with the source it was read from
+func Parse(data []byte, source string) ([]Rule, error) {
+	var rules []Rule
+	if err := yaml.UnmarshalStrict(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse policy from %s: %w", source, err)
+	}
+	for i := range rules {
+		rules[i].Source = source
+	}
+	return rules, nil
+}
+
+// Rules returns the rules of the policy in order
+func (p *Policy) Rules() []Rule {
+	return p.rules
+}
+
+// Evaluate returns the first rule matching the input, or nil if none match
+func (p *Policy) Evaluate(input Input) (*Rule, error) {
+	if p == nil || len(p.rules) == 0 {
+		return nil, nil
+	}
+	activation, err := input.activation()
+	if err != nil {
+		return nil, err
+	}
+	for i, rule := range p.rules {
+		out, _, err := rule.program.Eval(activation)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate policy rule %s: %w", rule.Name, err)
+		}
+		if matched, ok := out.Value().(bool); ok && matched {
+			return &p.rules[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (rule *Rule) compile() error {
+	if rule.Name == "" {
+		return fmt.Errorf("policy rule from %s has no name", rule.Source)
+	}
+	switch rule.Action {
+	case ActionExempt, ActionWarn, ActionStop, ActionDelete:
+		if rule.Threshold != "" {
+			return fmt.Errorf("policy rule %s: threshold is only allowed with action %s", rule.Name, ActionThreshold)
+		}
+	case ActionThreshold:
+		if rule.Threshold == "" {
+			return fmt.Errorf("policy rule %s: action %s requires a threshold", rule.Name, ActionThreshold)
+		}
+	default:
+		return fmt.Errorf("policy rule %s: invalid action %q, allowed values: exempt, warn, stop, delete or threshold", rule.Name, rule.Action)
+	}
+	ast, issues := env.Compile(rule.When)
+	if issues != nil && issues.Err() != nil {
+		return fmt.Errorf("policy rule %s: invalid expression: %w", rule.Name, issues.Err())
+	}
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return fmt.Errorf("policy rule %s: expression must return a bool, not %s", rule.Name, ast.OutputType())
+	}
+	if (rule.Action == ActionStop || rule.Action == ActionDelete) && !references(ast, daysInactiveVariable) {
+		return fmt.Errorf("policy rule %s: action %s acts regardless of the threshold, so the expression must use %s, e.g. %s >= 3", rule.Name, rule.Action, daysInactiveVariable, daysInactiveVariable)
+	}
+	program, err := env.Program(ast)
+	if err != nil {
+		return fmt.Errorf("policy rule %s: %w", rule.Name, err)
+	}
+	rule.program = program
+	return nil
+}
+
+// references tells if a compiled expression uses a variable
+func references(ast *cel.Ast, variable string) bool {
+	for _, reference := range ast.NativeRep().ReferenceMap() {
+		if reference.Name == variable {
+			return true
+		}
+	}
+	return false
+}
+
+func (input Input) activation() (map[string]any, error) {
+	rr, err := toMap(input.RadixRegistration)
+	if err != nil {
+		return nil, err
+	}
+	ra, err := toMap(input.RadixApplication)
+	if err != nil {
+		return nil, err
+	}
+	rd, err := toMap(input.RadixDeployment)
+	if err != nil {
+		return nil, err
+	}
+	rj, err := toMap(input.RadixJob)
+	if err != nil {
+		return nil, err
+	}
+	environments := []string{}
+	if input.RadixApplication != nil {
+		for _, environment := range input.RadixApplication.Spec.Environments {
+			environments = append(environments, environment.Name)
+		}
+	}
+	var appName, owner string
+	if input.RadixRegistration != nil {
+		appName, owner = input.RadixRegistration.Name, input.RadixRegistration.Spec.Owner
+	}
+	return map[string]any{
+		"rr":           rr,
+		"ra":           ra,
+		"rd":           rd,
+		"rj":           rj,
+		"action":       input.Action,
+		"appName":      appName,
+		"environment":  input.Environment,
+		"environments": environments,
+		"envCount":     len(environments),
+		"owner":        owner,
+		"daysInactive": input.DaysInactive,
+	}, nil
+}
+
+// toMap converts a resource to its JSON form, or an empty map if nil
+func toMap[T any](resource *T) (map[string]any, error) {
+	if resource == nil {
+		return map[string]any{}, nil
+	}
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -1,13 +1,64 @@
 package settings
 
 const (
-	InactiveDaysBeforeDeletionOption = "inactive-days-before-deletion"
-	InactiveDaysBeforeStopOption     = "inactive-days-before-stop"
-	CleanUpDaysOption                = "cleanup-days"
-	CleanUpStartOption               = "cleanup-start"
-	CleanUpEndOption                 = "cleanup-end"
-	CleanUpPeriodOption              = "period"
-	WhitelistOption                  = "whitelisted-rrs"
-	PrettyPrint                      = "pretty-print"
-	LogLevel                         = "log-level"
+	InactiveDaysBeforeDeletionOption    = "inactive-days-before-deletion"
+	InactiveDaysBeforeStopOption        = "inactive-days-before-stop"
+	MaxInactiveDaysBeforeDeletionOption = "max-inactive-days-before-deletion"
+	MaxInactiveDaysBeforeStopOption     = "max-inactive-days-before-stop"
+	CleanUpDaysOption                   = "cleanup-days"
+	CleanUpStartOption                  = "cleanup-start"
+	CleanUpEndOption                    = "cleanup-end"
+	CleanUpPeriodOption                 = "period"
+	WhitelistOption                     = "whitelisted-rrs"
+	WhitelistFileOption                 = "whitelist-file"
+	WhitelistConfigMapOption            = "whitelist-configmap"
+	PrettyPrint                         = "pretty-print"
+	LogLevel                            = "log-level"
+	RestoreAppOption                    = "app"
+	RestoreStoppedAfterOption           = "stopped-after"
+	RestoreAllOption                    = "all"
+	MetricsPortOption                   = "metrics-port"
+	DryRunOption                        = "dry-run"
+	OutputOption                        = "output"
+	ShowExemptOption                    = "show-exempt"
+	DeletionGracePeriodOption           = "deletion-grace-period"
+	WarnDaysBeforeStopOption            = "warn-days-before-stop"
+	WarnDaysBeforeDeletionOption        = "warn-days-before-deletion"
+	NotifierOption                      = "notifier"
+	NotifyWebhookUrlOption              = "notify-webhook-url"
+	SmtpHostOption                      = "smtp-host"
+	SmtpPortOption                      = "smtp-port"
+	SmtpFromOption                      = "smtp-from"
+	SmtpUsernameOption                  = "smtp-username"
+	MaxStopsPerRunOption                = "max-stops-per-run"
+	MaxStopsPercentPerRunOption         = "max-stops-percent-per-run"
+	MaxStopsPerDayOption                = "max-stops-per-day"
+	MaxStopsPercentPerDayOption         = "max-stops-percent-per-day"
+	MaxDeletionsPerRunOption            = "max-deletions-per-run"
+	MaxDeletionsPercentPerRunOption     = "max-deletions-percent-per-run"
+	MaxDeletionsPerDayOption            = "max-deletions-per-day"
+	MaxDeletionsPercentPerDayOption     = "max-deletions-percent-per-day"
+	CircuitBreakerConfigMapOption       = "circuit-breaker-configmap"
+	AllowNextRunOption                  = "allow-next-run"
+	BackupSinkOption                    = "backup-sink"
+	BackupDirOption                     = "backup-dir"
+	BackupS3EndpointOption              = "backup-s3-endpoint"
+	BackupS3BucketOption                = "backup-s3-bucket"
+	BackupS3RegionOption                = "backup-s3-region"
+	NoBackupOption                      = "no-backup"
+	TombstoneDirOption                  = "tombstone-dir"
+	TombstoneConfigMapOption            = "tombstone-configmap"
+	TriggerPipelineOption               = "trigger-pipeline"
+	PipelineImageOption                 = "pipeline-image"
+	ConcurrencyOption                   = "concurrency"
+	KubeApiQpsOption                    = "kube-api-qps"
+	KubeApiBurstOption                  = "kube-api-burst"
+	PolicyFileOption                    = "policy-file"
+	PolicyConfigMapOption               = "policy-configmap"
+	ConfigOption                        = "config"
+	LeaseNamespaceOption                = "lease-namespace"
+	LeaseNameOption                     = "lease-name"
+	LeaseDurationOption                 = "lease-duration"
+	EventDebounceOption                 = "event-debounce"
+	ShutdownGracePeriodOption           = "shutdown-grace-period"
 )
@@ -0,0 +1,126 @@
+package tombstone
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	corev1 "k8s.io/api/core/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+type dirStore struct {
+	dir string
+}
+
+// NewDirStore returns a Store keeping each tombstone as a file in a directory
+func NewDirStore(dir string) Store {
+	return &dirStore{dir: dir}
+}
+
+func (s *dirStore) Put(_ context.Context, tombstone Tombstone) error {
+	fileName, err := key(tombstone.AppName)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(tombstone, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(s.dir, fileName), data, 0o600)
+}
+
+func (s *dirStore) Get(_ context.Context, appName string) (*Tombstone, error) {
+	fileName, err := key(appName)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, fileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var tombstone Tombstone
+	if err := json.Unmarshal(data, &tombstone); err != nil {
+		return nil, fmt.Errorf("invalid tombstone for %s: %w", appName, err)
+	}
+	return &tombstone, nil
+}
+
+type configMapStore struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+}
+
+// NewConfigMapStore returns a Store keeping tombstones as keys in a ConfigMap, which is created on the first put.
+// A ConfigMap is limited to 1 MiB, so it suits clusters where few applications are deleted between cleanups of
+// the ConfigMap.
+func NewConfigMapStore(client kubernetes.Interface, namespace, name string) Store {
+	return &configMapStore{client: client, namespace: namespace, name: name}
+}
+
+func (s *configMapStore) Put(ctx context.Context, tombstone Tombstone) error {
+	dataKey, err := key(tombstone.AppName)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(tombstone)
+	if err != nil {
+		return err
+	}
+	configMaps := s.client.CoreV1().ConfigMaps(s.namespace)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap, err := configMaps.Get(ctx, s.name, metav1.GetOptions{})
+		if kubeerrors.IsNotFound(err) {
+			_, err = configMaps.Create(ctx, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: s.name, Namespace: s.namespace},
+				Data:       map[string]string{dataKey: string(data)},
+			}, metav1.CreateOptions{})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if configMap.Data == nil {
+			configMap.Data = make(map[string]string)
+		}
+		configMap.Data[dataKey] = string(data)
+		_, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+func (s *configMapStore) Get(ctx context.Context, appName string) (*Tombstone, error) {
+	dataKey, err := key(appName)
+	if err != nil {
+		return nil, err
+	}
+	configMap, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+	if kubeerrors.IsNotFound(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, ok := configMap.Data[dataKey]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	var tombstone Tombstone
+	if err := json.Unmarshal([]byte(data), &tombstone); err != nil {
+		return nil, fmt.Errorf("invalid tombstone for %s in configmap %s/%s: %w", appName, s.namespace, s.name, err)
+	}
+	return &tombstone, nil
+}
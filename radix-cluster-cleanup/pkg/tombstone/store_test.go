@@ -0,0 +1,43 @@
+package tombstone_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/equinor/radix-cluster-cleanup/pkg/tombstone"
+	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDirStore(t *testing.T) {
+	ctx := context.Background()
+	store := tombstone.NewDirStore(t.TempDir())
+	rr := v1.RadixRegistration{ObjectMeta: metav1.ObjectMeta{Name: "app"}, Spec: v1.RadixRegistrationSpec{ConfigBranch: "main"}}
+	rj := &v1.RadixJob{Spec: v1.RadixJobSpec{PipeLineType: v1.BuildDeploy, PipelineImage: "radix-pipeline:1", Build: v1.RadixBuildSpec{Branch: "main"}}}
+	if err := store.Put(ctx, tombstone.New(rr, nil, rj, time.Now())); err != nil {
+		t.Fatal(err)
+	}
+	deleted, err := store.Get(ctx, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted.RadixJob == nil || deleted.RadixJob.PipelineImage != "radix-pipeline:1" {
+		t.Errorf("expected the RadixJob spec to be recorded, got %v", deleted.RadixJob)
+	}
+}
+
+func TestDirStoreRejectsInvalidAppNames(t *testing.T) {
+	ctx := context.Background()
+	store := tombstone.NewDirStore(t.TempDir())
+	for _, appName := range []string{"../app", "a/b", "App", ""} {
+		if _, err := store.Get(ctx, appName); err == nil || errors.Is(err, tombstone.ErrNotFound) {
+			t.Errorf("expected %q to be rejected, got %v", appName, err)
+		}
+		rr := v1.RadixRegistration{ObjectMeta: metav1.ObjectMeta{Name: appName}}
+		if err := store.Put(ctx, tombstone.New(rr, nil, nil, time.Now())); err == nil {
+			t.Errorf("expected a tombstone for %q to be rejected", appName)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package tombstone
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ErrNotFound is returned when there is no tombstone for an application
+var ErrNotFound = errors.New("tombstone not found")
+
+// Tombstone records a RadixRegistration deleted by the cleanup, so it can be re-created
+type Tombstone struct {
+	AppName           string                   `json:"appName"`
+	DeletedAt         time.Time                `json:"deletedAt"`
+	Labels            map[string]string        `json:"labels,omitempty"`
+	RadixRegistration v1.RadixRegistrationSpec `json:"radixRegistration"`
+	RadixApplication  *v1.RadixApplicationSpec `json:"radixApplication,omitempty"`
+	// RadixJob is the spec of the latest build-deploy RadixJob, copied for the pipeline job triggered on restore
+	RadixJob *v1.RadixJobSpec `json:"radixJob,omitempty"`
+}
+
+// SecretRef is a secret an owner must re-enter after the application is re-created
+type SecretRef struct {
+	Component string `json:"component"`
+	Name      string `json:"name"`
+}
+
+// Store keeps tombstones of deleted applications
+type Store interface {
+	Put(ctx context.Context, tombstone Tombstone) error
+	// Get returns the tombstone of an application, or ErrNotFound
+	Get(ctx context.Context, appName string) (*Tombstone, error)
+}
+
+// New returns a tombstone for a RadixRegistration, its RadixApplication and its latest build-deploy RadixJob, which may
+// be nil. The webhook shared secret is left out, as the store is not meant for secrets.
+func New(rr v1.RadixRegistration, ra *v1.RadixApplication, rj *v1.RadixJob, deletedAt time.Time) Tombstone {
+	tombstone := Tombstone{
+		AppName:           rr.Name,
+		DeletedAt:         deletedAt.UTC(),
+		Labels:            rr.Labels,
+		RadixRegistration: rr.Spec,
+	}
+	tombstone.RadixRegistration.SharedSecret = ""
+	if ra != nil {
+		tombstone.RadixApplication = &ra.Spec
+	}
+	if rj != nil {
+		tombstone.RadixJob = &rj.Spec
+	}
+	return tombstone
+}
+
+// ValidateAppName returns an error if an application name is not a DNS label, as it is used in file names and keys
+func ValidateAppName(appName string) error {
+	if errs := validation.IsDNS1123Label(appName); len(errs) > 0 {
+		return fmt.Errorf("invalid application name %q: %s", appName, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// Secrets returns the component and job secrets declared in the RadixApplication, sorted by component and name
+func (t Tombstone) Secrets() []SecretRef {
+	if t.RadixApplication == nil {
+		return nil
+	}
+	var secrets []SecretRef
+	for _, component := range t.RadixApplication.Components {
+		for _, secret := range component.Secrets {
+			secrets = append(secrets, SecretRef{Component: component.Name, Name: secret})
+		}
+	}
+	for _, job := range t.RadixApplication.Jobs {
+		for _, secret := range job.Secrets {
+			secrets = append(secrets, SecretRef{Component: job.Name, Name: secret})
+		}
+	}
+	slices.SortFunc(secrets, func(a, b SecretRef) int {
+		return cmp.Or(cmp.Compare(a.Component, b.Component), cmp.Compare(a.Name, b.Name))
+	})
+	return secrets
+}
+
+func key(appName string) (string, error) {
+	if err := ValidateAppName(appName); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s.json", appName), nil
+}
@@ -0,0 +1,146 @@
+package whitelist
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Entry matches RadixRegistration names by exact name, glob pattern or regular expression
+type Entry struct {
+	Name    string `json:"name,omitempty"`
+	Pattern string `json:"pattern,omitempty"`
+	Regex   string `json:"regex,omitempty"`
+	Comment string `json:"comment,omitempty"`
+	Expires string `json:"expires,omitempty"`
+	Source  string `json:"-"`
+
+	regex   *regexp.Regexp
+	expires *time.Time
+}
+
+// Whitelist is a set of entries for RadixRegistrations to exclude from cleanup
+type Whitelist struct {
+	entries []Entry
+}
+
+// New returns a Whitelist with the given entries, validating patterns, regular expressions and expiry dates
+func New(entries ...Entry) (*Whitelist, error) {
+	w := &Whitelist{}
+	if err := w.Add(entries...); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Parse reads a YAML list of entries, tagging each with the source it was read from
+func Parse(data []byte, source string) ([]Entry, error) {
+	var entries []Entry
+	if err := yaml.UnmarshalStrict(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse whitelist from %s: %w", source, err)
+	}
+	for i := range entries {
+		entries[i].Source = source
+	}
+	return entries, nil
+}
+
+// Add validates and appends entries to the whitelist
+func (w *Whitelist) Add(entries ...Entry) error {
+	for _, entry := range entries {
+		if err := entry.compile(); err != nil {
+			return err
+		}
+		w.entries = append(w.entries, entry)
+	}
+	return nil
+}
+
+// Match returns a description of the first unexpired entry matching the name, and false if none match
+func (w *Whitelist) Match(name string, now time.Time) (string, bool) {
+	if w == nil {
+		return "", false
+	}
+	for _, entry := range w.entries {
+		if entry.expires != nil && !now.Before(*entry.expires) {
+			continue
+		}
+		if entry.matches(name) {
+			return entry.String(), true
+		}
+	}
+	return "", false
+}
+
+func (e *Entry) compile() error {
+	set := 0
+	for _, value := range []string{e.Name, e.Pattern, e.Regex} {
+		if value != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("whitelist entry from %s must have exactly one of name, pattern or regex", e.Source)
+	}
+	if e.Pattern != "" {
+		if _, err := path.Match(e.Pattern, ""); err != nil {
+			return fmt.Errorf("invalid whitelist pattern %q from %s: %w", e.Pattern, e.Source, err)
+		}
+	}
+	if e.Regex != "" {
+		regex, err := regexp.Compile(e.Regex)
+		if err != nil {
+			return fmt.Errorf("invalid whitelist regex %q from %s: %w", e.Regex, e.Source, err)
+		}
+		e.regex = regex
+	}
+	if e.Expires != "" {
+		expires, err := ParseExpiry(e.Expires)
+		if err != nil {
+			return fmt.Errorf("invalid whitelist expiry %q from %s: %w", e.Expires, e.Source, err)
+		}
+		e.expires = &expires
+	}
+	return nil
+}
+
+func (e *Entry) matches(name string) bool {
+	switch {
+	case e.Name != "":
+		return e.Name == name
+	case e.Pattern != "":
+		matched, _ := path.Match(e.Pattern, name)
+		return matched
+	default:
+		return e.regex.MatchString(name)
+	}
+}
+
+// String describes the entry, its source and comment
+func (e *Entry) String() string {
+	description := fmt.Sprintf("whitelisted by %s", e.Source)
+	switch {
+	case e.Pattern != "":
+		description = fmt.Sprintf("%s pattern %s", description, e.Pattern)
+	case e.Regex != "":
+		description = fmt.Sprintf("%s regex %s", description, e.Regex)
+	}
+	if e.Expires != "" {
+		description = fmt.Sprintf("%s until %s", description, e.Expires)
+	}
+	if e.Comment != "" {
+		description = fmt.Sprintf("%s: %s", description, e.Comment)
+	}
+	return description
+}
+
+// ParseExpiry parses an expiry as either a date, which expires at the end of the day in UTC, or an RFC3339 timestamp
+func ParseExpiry(value string) (time.Time, error) {
+	if date, err := time.Parse(time.DateOnly, value); err == nil {
+		return date.AddDate(0, 0, 1), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
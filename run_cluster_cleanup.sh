@@ -1,6 +1,2 @@
 #!/bin/sh
-LOG_LEVEL=${LOG_LEVEL} ./radix-cluster-cleanup "${COMMAND}" \
-  --period=${PERIOD} \
-  --cleanup-start=${CLEANUP_START} \
-  --cleanup-end=${CLEANUP_END} \
-  --cleanup-days=${CLEANUP_DAYS} >/dev/null
\ No newline at end of file
+./radix-cluster-cleanup "${COMMAND}" >/dev/null
\ No newline at end of file